@@ -0,0 +1,109 @@
+package dexscreener
+
+import (
+	"context"
+	"fmt"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/chains"
+	"pnl-scan-tool/package/utils"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"strings"
+)
+
+// Source serves token prices and holders from DEX Screener.
+type Source struct{}
+
+func (Source) Name() string {
+	return "dexscreener"
+}
+
+func (Source) TradedTokens(ctx context.Context, chain string, wallet string, days int) ([]source.Token, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (Source) Trades(ctx context.Context, chain string, wallet string, token string) (pnlmodel.TradeHistory, error) {
+	return pnlmodel.TradeHistory{}, source.ErrNotSupported
+}
+
+func (Source) TopTraders(ctx context.Context, chain string, token string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (Source) TopHolders(ctx context.Context, chainID string, token string) ([]string, error) {
+	chain, ok := dexScreenerChain(chainID)
+
+	if !ok {
+		return nil, source.ErrNotSupported
+	}
+
+	tokenInfo, err := TokenInfomation(ctx, chain.DexScreenerID, token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	holders := make([]string, 0, len(tokenInfo.GP.Holders))
+	for _, holder := range tokenInfo.GP.Holders {
+		if holder.IsContract || holder.IsLocked {
+			continue
+		}
+		holders = append(holders, holder.Address)
+	}
+
+	return holders, nil
+}
+
+// Price uses the most liquid pair of the token. The quote price is only set
+// when that pair is quoted in the chain's native token.
+func (Source) Price(ctx context.Context, chainID string, token string) (source.Price, error) {
+	chain, ok := dexScreenerChain(chainID)
+
+	if !ok {
+		return source.Price{}, source.ErrNotSupported
+	}
+
+	pairs, err := TokenPairsInfomation(ctx, token)
+
+	if err != nil {
+		return source.Price{}, err
+	}
+
+	var best *Pair
+
+	for i, pair := range pairs {
+		if pair.ChainId != chain.DexScreenerID || !strings.EqualFold(pair.BaseToken.Address, token) {
+			continue
+		}
+
+		if best == nil || pair.Liquidity.Usd > best.Liquidity.Usd {
+			best = &pairs[i]
+		}
+	}
+
+	if best == nil {
+		return source.Price{}, fmt.Errorf("%w: no %s pair for %s", source.ErrNotFound, chain.DexScreenerID, token)
+	}
+
+	price := source.Price{
+		Symbol:   best.BaseToken.Symbol,
+		PriceUSD: utils.ConvertStringToFloat64(best.PriceUsd),
+	}
+
+	if chain.IsQuote(best.QuoteToken.Address) {
+		price.PriceQuote = utils.ConvertStringToFloat64(best.PriceNative)
+	}
+
+	return price, nil
+}
+
+// dexScreenerChain returns the registered chain with id if DEX Screener
+// covers it.
+func dexScreenerChain(id string) (chains.Chain, bool) {
+	chain, err := chains.Get(id)
+
+	if err != nil || chain.DexScreenerID == "" {
+		return chains.Chain{}, false
+	}
+
+	return chain, true
+}
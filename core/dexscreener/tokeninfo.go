@@ -1,8 +1,10 @@
 package dexscreener
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"pnl-scan-tool/package/cache"
 )
 
 type TokenInfo struct {
@@ -190,22 +192,32 @@ type TokenInfo struct {
 	} `json:"ll"`
 }
 
-func TokenInfomation(chain string, tokenAddress string) (TokenInfo, error) {
+func TokenInfomation(ctx context.Context, chain string, tokenAddress string) (TokenInfo, error) {
 	var tokenInfo TokenInfo
 
 	apiUrl := fmt.Sprintf("https://io.dexscreener.com/dex/pair-details/v3/%s/%s", chain, tokenAddress)
 
-	data, err := fetchWithRetry(apiUrl)
+	data, cached := cache.Get(ctx, cache.Price, apiUrl)
 
-	if err != nil {
-		return tokenInfo, err
+	if !cached {
+		var err error
+
+		data, err = fetch(ctx, apiUrl)
+
+		if err != nil {
+			return tokenInfo, err
+		}
 	}
 
-	err = json.Unmarshal(data, &tokenInfo)
+	err := json.Unmarshal(data, &tokenInfo)
 
 	if err != nil {
 		return tokenInfo, err
 	}
 
+	if !cached {
+		cache.Set(ctx, cache.Price, apiUrl, data)
+	}
+
 	return tokenInfo, nil
 }
@@ -0,0 +1,64 @@
+package dexscreener
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
+)
+
+type PairToken struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+	Symbol  string `json:"symbol"`
+}
+
+type Pair struct {
+	ChainId     string    `json:"chainId"`
+	DexId       string    `json:"dexId"`
+	PairAddress string    `json:"pairAddress"`
+	BaseToken   PairToken `json:"baseToken"`
+	QuoteToken  PairToken `json:"quoteToken"`
+	PriceNative string    `json:"priceNative"`
+	PriceUsd    string    `json:"priceUsd"`
+	Liquidity   struct {
+		Usd float64 `json:"usd"`
+	} `json:"liquidity"`
+}
+
+type TokenPairs struct {
+	Pairs []Pair `json:"pairs"`
+}
+
+// TokenPairsInfomation fetches the pairs a token trades in from the public DEX
+// Screener API.
+func TokenPairsInfomation(ctx context.Context, tokenAddress string) ([]Pair, error) {
+	apiUrl := fmt.Sprintf("https://api.dexscreener.com/latest/dex/tokens/%s", tokenAddress)
+
+	data, cached := cache.Get(ctx, cache.Price, apiUrl)
+
+	if !cached {
+		var err error
+
+		data, err = fetch(ctx, apiUrl)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var tokenPairs TokenPairs
+
+	err := json.Unmarshal(data, &tokenPairs)
+
+	if err != nil {
+		return nil, source.SchemaError(err)
+	}
+
+	if !cached {
+		cache.Set(ctx, cache.Price, apiUrl, data)
+	}
+
+	return tokenPairs.Pairs, nil
+}
@@ -0,0 +1,183 @@
+package evmrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/utils"
+)
+
+// Client calls an EVM JSON-RPC endpoint.
+type Client struct {
+	Endpoint string
+	// RecordDir, when set, receives a copy of every result so that it can be
+	// served again by ReplayHandler.
+	RecordDir string
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// NewClient creates a client for the given endpoint.
+func NewClient(endpoint string) *Client {
+	return &Client{Endpoint: endpoint}
+}
+
+// call invokes method and decodes its result into result.
+func (c *Client) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	// utils.Client paces the endpoint and retries it while it rate limits
+	resp, err := utils.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch data: %w", err)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("evm rpc %s failed: %w: %s", method, source.StatusError(resp.StatusCode), data)
+	}
+
+	var response rpcResponse
+
+	if err := json.Unmarshal(data, &response); err != nil {
+		return source.SchemaError(err)
+	}
+
+	if response.Error != nil {
+		return response.Error
+	}
+
+	if c.RecordDir != "" {
+		if err := c.record(method, params, response.Result); err != nil {
+			fmt.Println("Error recording EVM RPC response:", err)
+		}
+	}
+
+	return json.Unmarshal(response.Result, result)
+}
+
+func (c *Client) record(method string, params []interface{}, result json.RawMessage) error {
+	if err := os.MkdirAll(c.RecordDir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(c.RecordDir, recordingName(method, params)), result, 0644)
+}
+
+// recordingName names the file holding the result of a call after its method
+// and a hash of its parameters, which are objects for most EVM calls.
+func recordingName(method string, params []interface{}) string {
+	encoded, _ := json.Marshal(params)
+	sum := sha1.Sum(encoded)
+
+	return method + "_" + hex.EncodeToString(sum[:8]) + ".json"
+}
+
+// BlockNumber returns the latest block number.
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var number string
+
+	if err := c.call(ctx, "eth_blockNumber", []interface{}{}, &number); err != nil {
+		return 0, err
+	}
+
+	return hexToUint64(number), nil
+}
+
+// GetLogs returns the logs between two blocks matching topics. A nil topic
+// matches anything.
+func (c *Client) GetLogs(ctx context.Context, fromBlock uint64, toBlock uint64, topics []interface{}) ([]Log, error) {
+	filter := map[string]interface{}{
+		"fromBlock": uint64ToHex(fromBlock),
+		"toBlock":   uint64ToHex(toBlock),
+		"topics":    topics,
+	}
+
+	var logs []Log
+
+	err := c.call(ctx, "eth_getLogs", []interface{}{filter}, &logs)
+
+	return logs, err
+}
+
+// GetTransactionReceipt returns the receipt of a mined transaction.
+func (c *Client) GetTransactionReceipt(ctx context.Context, txHash string) (*Receipt, error) {
+	var receipt *Receipt
+
+	err := c.call(ctx, "eth_getTransactionReceipt", []interface{}{txHash}, &receipt)
+
+	return receipt, err
+}
+
+// GetBlockByNumber returns a block header without its transactions.
+func (c *Client) GetBlockByNumber(ctx context.Context, number uint64) (*Block, error) {
+	var block *Block
+
+	err := c.call(ctx, "eth_getBlockByNumber", []interface{}{uint64ToHex(number), false}, &block)
+
+	return block, err
+}
+
+// Call runs a read-only contract call against the latest block.
+func (c *Client) Call(ctx context.Context, to string, data string) (string, error) {
+	var result string
+
+	err := c.call(ctx, "eth_call", []interface{}{map[string]interface{}{"to": to, "data": data}, "latest"}, &result)
+
+	return result, err
+}
+
+// CallAt is Call against the state at block. Blocks older than the recent
+// ones need an archive node.
+func (c *Client) CallAt(ctx context.Context, to string, data string, block uint64) (string, error) {
+	var result string
+
+	err := c.call(ctx, "eth_call", []interface{}{map[string]interface{}{"to": to, "data": data}, uint64ToHex(block)}, &result)
+
+	return result, err
+}
@@ -0,0 +1,76 @@
+package evmrpc
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+func hexToUint64(value string) uint64 {
+	number, _ := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+	return number
+}
+
+func hexToBig(value string) *big.Int {
+	number, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16)
+	if !ok {
+		return new(big.Int)
+	}
+	return number
+}
+
+func uint64ToHex(value uint64) string {
+	return "0x" + strconv.FormatUint(value, 16)
+}
+
+// word returns the i-th 32 byte word of ABI encoded data as an unsigned
+// integer.
+func word(data string, i int) *big.Int {
+	data = strings.TrimPrefix(data, "0x")
+
+	start := i * 64
+	if len(data) < start+64 {
+		return new(big.Int)
+	}
+
+	value, _ := new(big.Int).SetString(data[start:start+64], 16)
+	if value == nil {
+		return new(big.Int)
+	}
+
+	return value
+}
+
+// signedWord returns the i-th word of ABI encoded data as an int256.
+func signedWord(data string, i int) *big.Int {
+	value := word(data, i)
+
+	if value.Bit(255) == 1 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+
+	return value
+}
+
+// topicAddress returns the address held by an indexed topic.
+func topicAddress(topic string) string {
+	topic = strings.TrimPrefix(topic, "0x")
+
+	if len(topic) < 40 {
+		return ""
+	}
+
+	return "0x" + strings.ToLower(topic[len(topic)-40:])
+}
+
+// addressTopic pads an address into an indexed topic.
+func addressTopic(address string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(address, "0x"))
+}
+
+// toFloat scales a raw token amount by its decimals.
+func toFloat(value *big.Int, decimals int) float64 {
+	amount, _ := new(big.Float).SetInt(value).Float64()
+	return amount / math.Pow10(decimals)
+}
@@ -0,0 +1,374 @@
+package evmrpc
+
+import (
+	"context"
+	"fmt"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/chains"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCachedWallets bounds the decoded wallets kept between calls.
+	maxCachedWallets = 16
+	// walletCacheTTL is how long a decoded wallet is reused by Trades.
+	walletCacheTTL = 10 * time.Minute
+	// minLogRange is the smallest block range a log query is split into.
+	minLogRange = 1000
+)
+
+// Source serves EVM trades decoded from a JSON-RPC node. It only prices the
+// native coin, so it is meant to be paired with a price feed in a
+// source.Fallback.
+type Source struct {
+	Client *Client
+	// Chain is the chain this node serves, e.g. "eth".
+	Chain string
+	// WrappedNative and Stablecoins are the quote tokens, lower case.
+	WrappedNative string
+	Stablecoins   []string
+	// BlockSeconds is the average block time, used to find the first block
+	// of a scan window.
+	BlockSeconds float64
+	// StartBlock is where all-time scans start, set from the chain registry.
+	StartBlock uint64
+	// NativeUSDPool is the pair the native coin is priced in USD from, at
+	// the block of each trade. Without it USD figures are left at 0 and
+	// stablecoin trades are skipped.
+	NativeUSDPool string
+
+	mu            sync.Mutex
+	wallets       map[string]walletEvents
+	pools         map[string][2]string
+	tokenDecimals map[string]int
+	blockTimes    map[uint64]int64
+	nativePrices  map[uint64]float64
+}
+
+// NewSource creates a source for a registered EVM chain reading from the
+// given RPC endpoint.
+func NewSource(chain chains.Chain, endpoint string) *Source {
+	return &Source{
+		Client:        NewClient(endpoint),
+		Chain:         chain.ID,
+		WrappedNative: chain.WrappedNative,
+		Stablecoins:   chain.Stablecoins,
+		BlockSeconds:  chain.BlockSeconds,
+		StartBlock:    chain.StartBlock,
+		NativeUSDPool: chain.NativeUSDPool,
+		wallets:       make(map[string]walletEvents),
+		pools:         make(map[string][2]string),
+		tokenDecimals: make(map[string]int),
+		blockTimes:    make(map[uint64]int64),
+		nativePrices:  make(map[uint64]float64),
+	}
+}
+
+func (s *Source) Name() string {
+	return "evmrpc-" + s.Chain
+}
+
+// TradedTokens decodes the transactions of the wallet of the last days days,
+// or all of them when days is 0, and lists the tokens they moved, most recent
+// first.
+func (s *Source) TradedTokens(ctx context.Context, chain string, wallet string, days int) ([]source.Token, error) {
+	if chain != s.Chain {
+		return nil, source.ErrNotSupported
+	}
+
+	events, err := s.read(ctx, wallet, days)
+
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var tokens []source.Token
+
+	for i := len(events) - 1; i >= 0; i-- {
+		token := events[i].Token
+
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, source.Token{Address: token})
+		}
+	}
+
+	return tokens, nil
+}
+
+// Trades returns the decoded trades of one token over the whole history of
+// the wallet, whatever window TradedTokens listed it from, so that the buys
+// made before the window still count in the cost basis.
+func (s *Source) Trades(ctx context.Context, chain string, wallet string, token string) (pnlmodel.TradeHistory, error) {
+	if chain != s.Chain {
+		return pnlmodel.TradeHistory{}, source.ErrNotSupported
+	}
+
+	s.mu.Lock()
+	read, ok := s.wallets[cacheKey(wallet, 0)]
+	s.mu.Unlock()
+
+	events := read.events
+
+	if !ok || time.Since(read.readAt) > walletCacheTTL {
+		var err error
+
+		if events, err = s.read(ctx, wallet, 0); err != nil {
+			return pnlmodel.TradeHistory{}, err
+		}
+	}
+
+	token = strings.ToLower(token)
+	history := pnlmodel.TradeHistory{TokenAddress: token}
+
+	for _, event := range events {
+		if event.Token == token {
+			history.EventTrades = append(history.EventTrades, event.EventTrade)
+		}
+	}
+
+	return history, nil
+}
+
+func (s *Source) TopTraders(ctx context.Context, chain string, token string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (s *Source) TopHolders(ctx context.Context, chain string, token string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (s *Source) Price(ctx context.Context, chain string, token string) (source.Price, error) {
+	return source.Price{}, source.ErrNotSupported
+}
+
+// read finds the transactions of the last days days that moved tokens in or
+// out of a wallet, decodes them oldest first and caches them for the calls
+// that follow. A windowed read that finds transactions newer than the cached
+// whole history drops it, so that Trades reads it again.
+func (s *Source) read(ctx context.Context, wallet string, days int) ([]TokenEvent, error) {
+	wallet = strings.ToLower(wallet)
+
+	latest, err := s.Client.BlockNumber(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fromBlock := s.StartBlock
+
+	if days != 0 && s.BlockSeconds > 0 {
+		window := uint64(float64(days*24*60*60) / s.BlockSeconds)
+
+		if window < latest && latest-window > fromBlock {
+			fromBlock = latest - window
+		}
+	}
+
+	topic := addressTopic(wallet)
+
+	sent, err := s.logs(ctx, fromBlock, latest, []interface{}{transferTopic, topic})
+
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := s.logs(ctx, fromBlock, latest, []interface{}{transferTopic, nil, topic})
+
+	if err != nil {
+		return nil, err
+	}
+
+	txBlocks := make(map[string]uint64)
+
+	for _, log := range append(sent, received...) {
+		txBlocks[log.TransactionHash] = hexToUint64(log.BlockNumber)
+	}
+
+	txHashes := make([]string, 0, len(txBlocks))
+	for txHash := range txBlocks {
+		txHashes = append(txHashes, txHash)
+	}
+
+	sort.Slice(txHashes, func(i, j int) bool {
+		if txBlocks[txHashes[i]] != txBlocks[txHashes[j]] {
+			return txBlocks[txHashes[i]] < txBlocks[txHashes[j]]
+		}
+		return txHashes[i] < txHashes[j]
+	})
+
+	var events []TokenEvent
+
+	for _, txHash := range txHashes {
+		receipt, err := s.Client.GetTransactionReceipt(ctx, txHash)
+
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp, err := s.timestamp(ctx, txBlocks[txHash])
+
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, s.DecodeReceipt(ctx, receipt, wallet, timestamp)...)
+	}
+
+	read := walletEvents{events: events, readAt: time.Now()}
+
+	if len(txHashes) != 0 {
+		read.newest = txHashes[len(txHashes)-1]
+	}
+
+	s.mu.Lock()
+	if all, ok := s.wallets[cacheKey(wallet, 0)]; ok && days != 0 && read.newest != "" && all.newest != read.newest {
+		delete(s.wallets, cacheKey(wallet, 0))
+	}
+	if len(s.wallets) >= maxCachedWallets {
+		for cached := range s.wallets {
+			delete(s.wallets, cached)
+			break
+		}
+	}
+	s.wallets[cacheKey(wallet, days)] = read
+	s.mu.Unlock()
+
+	return events, nil
+}
+
+// walletEvents are the decoded transactions of a wallet. Newest is the hash
+// of the latest transaction read.
+type walletEvents struct {
+	events []TokenEvent
+	newest string
+	readAt time.Time
+}
+
+// cacheKey keys the decoded transactions of a wallet on the window they were
+// read over.
+func cacheKey(wallet string, days int) string {
+	return fmt.Sprintf("%s/%d", strings.ToLower(wallet), days)
+}
+
+// logs queries logs over a block range, splitting the range in halves when
+// the node refuses it as too large.
+func (s *Source) logs(ctx context.Context, fromBlock uint64, toBlock uint64, topics []interface{}) ([]Log, error) {
+	logs, err := s.Client.GetLogs(ctx, fromBlock, toBlock, topics)
+
+	if err == nil {
+		return logs, nil
+	}
+
+	if _, isRPCError := err.(*rpcError); !isRPCError || toBlock-fromBlock < minLogRange {
+		return nil, err
+	}
+
+	middle := fromBlock + (toBlock-fromBlock)/2
+
+	first, err := s.logs(ctx, fromBlock, middle, topics)
+
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := s.logs(ctx, middle+1, toBlock, topics)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(first, second...), nil
+}
+
+// timestamp returns the time of a block.
+func (s *Source) timestamp(ctx context.Context, number uint64) (int64, error) {
+	s.mu.Lock()
+	timestamp, ok := s.blockTimes[number]
+	s.mu.Unlock()
+
+	if ok {
+		return timestamp, nil
+	}
+
+	block, err := s.Client.GetBlockByNumber(ctx, number)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if block == nil {
+		return 0, fmt.Errorf("block %d not found", number)
+	}
+
+	timestamp = int64(hexToUint64(block.Timestamp))
+
+	s.mu.Lock()
+	s.blockTimes[number] = timestamp
+	s.mu.Unlock()
+
+	return timestamp, nil
+}
+
+// poolTokens returns the two tokens of a Uniswap style pool.
+func (s *Source) poolTokens(ctx context.Context, pool string) ([2]string, bool) {
+	pool = strings.ToLower(pool)
+
+	s.mu.Lock()
+	pair, ok := s.pools[pool]
+	s.mu.Unlock()
+
+	if ok {
+		return pair, pair[0] != ""
+	}
+
+	token0, err0 := s.Client.Call(ctx, pool, token0Selector)
+	token1, err1 := s.Client.Call(ctx, pool, token1Selector)
+
+	if err0 == nil && err1 == nil {
+		pair = [2]string{topicAddress(token0), topicAddress(token1)}
+	}
+
+	if ctx.Err() != nil {
+		return pair, false
+	}
+
+	// Failures are cached too, the pool is not a Uniswap pool
+	s.mu.Lock()
+	s.pools[pool] = pair
+	s.mu.Unlock()
+
+	return pair, pair[0] != ""
+}
+
+// decimals returns the decimals of a token, 18 when it does not say.
+func (s *Source) decimals(ctx context.Context, token string) int {
+	s.mu.Lock()
+	decimals, ok := s.tokenDecimals[token]
+	s.mu.Unlock()
+
+	if ok {
+		return decimals
+	}
+
+	decimals = 18
+
+	if result, err := s.Client.Call(ctx, token, decimalsSelector); err == nil && len(result) > 2 {
+		decimals = int(word(result, 0).Int64())
+	}
+
+	if ctx.Err() != nil {
+		return decimals
+	}
+
+	s.mu.Lock()
+	s.tokenDecimals[token] = decimals
+	s.mu.Unlock()
+
+	return decimals
+}
@@ -0,0 +1,231 @@
+package evmrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pnl-scan-tool/core/source/sourcetest"
+	"pnl-scan-tool/package/chains"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+// The recordings hold four transactions of testWallet on eth: a Uniswap V2
+// buy of testToken for WETH, a Uniswap V3 sell of it for USDC, a V2 swap
+// between two other tokens and a transfer of testToken from testSender.
+const (
+	testWallet = "0x52908400098527886e0f7030069857d2e4169ee7"
+	testSender = "0x8ba1f109551bd432803012645ac136ddd64dba72"
+	testToken  = "0x1f9840a85d5af5bf1d1762f925bdaddc4201f984"
+	usdc       = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	routedIn   = "0x514910771af9ca656af840dff83e8264ecf986ca"
+	routedOut  = "0x7fc66500c84a76ad7e9c93437bfc5ac33e2ddae9"
+	replayDir  = "testdata/replay"
+)
+
+var (
+	buyTx      = "0x" + strings.Repeat("1", 64)
+	sellTx     = "0x" + strings.Repeat("2", 64)
+	routedTx   = "0x" + strings.Repeat("3", 64)
+	transferTx = "0x" + strings.Repeat("4", 64)
+)
+
+func newReplaySource(t *testing.T) *Source {
+	t.Helper()
+
+	server := httptest.NewServer(ReplayHandler(replayDir))
+	t.Cleanup(server.Close)
+
+	chain, err := chains.Get("eth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewSource(chain, server.URL)
+}
+
+func loadReceipt(t *testing.T, txHash string) *Receipt {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(replayDir, recordingName("eth_getTransactionReceipt", []interface{}{txHash})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var receipt Receipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	return &receipt
+}
+
+var (
+	// ETH is worth 2000 USD at the block of the buy, from the reserves of
+	// the eth NativeUSDPool
+	wantBuy = pnlmodel.EventTrade{
+		TxHash:         buyTx,
+		EventType:      pnlmodel.EventBuy,
+		TokensAmount:   1000,
+		QuoteAmount:    1,
+		QuoteAmountUSD: 2000,
+		Price:          0.001,
+		Fee:            0.004,
+		Timestamp:      1600010800,
+	}
+	// ETH is worth 2100 USD at the block of the sell
+	wantSell = pnlmodel.EventTrade{
+		TxHash:         sellTx,
+		EventType:      pnlmodel.EventSell,
+		TokensAmount:   400,
+		QuoteAmount:    800.0 / 2100,
+		QuoteAmountUSD: 800,
+		Price:          800.0 / 2100 / 400,
+		Fee:            0.002,
+		Timestamp:      1600011400,
+	}
+	wantTransfer = pnlmodel.EventTrade{
+		TxHash:       transferTx,
+		EventType:    pnlmodel.EventTransferIn,
+		TokensAmount: 10,
+		Counterparty: testSender,
+		Timestamp:    1600011760,
+	}
+)
+
+func TestDecodeReceiptV2Buy(t *testing.T) {
+	s := newReplaySource(t)
+
+	events := s.DecodeReceipt(context.Background(), loadReceipt(t, buyTx), testWallet, wantBuy.Timestamp)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	if events[0].Token != testToken || events[0].Program != "uniswap-v2" {
+		t.Errorf("event = %s on %s, want %s on uniswap-v2", events[0].Token, events[0].Program, testToken)
+	}
+
+	sourcetest.AssertTrade(t, events[0].EventTrade, wantBuy)
+}
+
+func TestDecodeReceiptV3StablecoinSell(t *testing.T) {
+	s := newReplaySource(t)
+
+	events := s.DecodeReceipt(context.Background(), loadReceipt(t, sellTx), testWallet, wantSell.Timestamp)
+
+	// The USDC received is the quote leg, not a trade of its own
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	if events[0].Token != testToken || events[0].Program != "uniswap-v3" {
+		t.Errorf("event = %s on %s, want %s on uniswap-v3", events[0].Token, events[0].Program, testToken)
+	}
+
+	sourcetest.AssertTrade(t, events[0].EventTrade, wantSell)
+}
+
+func TestDecodeReceiptSkipsRoutedSwap(t *testing.T) {
+	s := newReplaySource(t)
+
+	if events := s.DecodeReceipt(context.Background(), loadReceipt(t, routedTx), testWallet, 0); len(events) != 0 {
+		t.Errorf("got %d events for a swap between two tokens, want none", len(events))
+	}
+}
+
+func TestDecodeReceiptTransferIn(t *testing.T) {
+	s := newReplaySource(t)
+
+	events := s.DecodeReceipt(context.Background(), loadReceipt(t, transferTx), testWallet, wantTransfer.Timestamp)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	// The sender paid the gas
+	sourcetest.AssertTrade(t, events[0].EventTrade, wantTransfer)
+}
+
+func TestSourceReplay(t *testing.T) {
+	s := newReplaySource(t)
+	ctx := context.Background()
+
+	tokens, err := s.TradedTokens(ctx, "eth", testWallet, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 1 || tokens[0].Address != testToken {
+		t.Fatalf("tokens = %+v, want %s only", tokens, testToken)
+	}
+
+	history, err := s.Trades(ctx, "eth", testWallet, testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.EventTrades) != 3 {
+		t.Fatalf("got %d trades of %s, want 3", len(history.EventTrades), testToken)
+	}
+
+	sourcetest.AssertTrade(t, history.EventTrades[0], wantBuy)
+	sourcetest.AssertTrade(t, history.EventTrades[1], wantSell)
+	sourcetest.AssertTrade(t, history.EventTrades[2], wantTransfer)
+
+	for _, token := range []string{usdc, routedIn, routedOut} {
+		history, err := s.Trades(ctx, "eth", testWallet, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(history.EventTrades) != 0 {
+			t.Errorf("got %d trades of %s, want none", len(history.EventTrades), token)
+		}
+	}
+}
+
+func TestSourceTradesIgnoresWindow(t *testing.T) {
+	s := newReplaySource(t)
+	ctx := context.Background()
+
+	// Every recorded trade is older than the last day of blocks
+	tokens, err := s.TradedTokens(ctx, "eth", testWallet, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 0 {
+		t.Fatalf("got %d tokens traded in the last day, want none", len(tokens))
+	}
+
+	history, err := s.Trades(ctx, "eth", testWallet, testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.EventTrades) != 3 {
+		t.Errorf("got %d trades of %s after a windowed read, want the 3 of its whole history", len(history.EventTrades), testToken)
+	}
+}
+
+func TestSourceTradesRereadsExpiredWallet(t *testing.T) {
+	s := newReplaySource(t)
+
+	// A whole history read before the wallet traded, too old to reuse
+	s.wallets[cacheKey(testWallet, 0)] = walletEvents{readAt: time.Now().Add(-time.Hour)}
+
+	history, err := s.Trades(context.Background(), "eth", testWallet, testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.EventTrades) != 3 {
+		t.Errorf("got %d trades of %s, want the 3 read again", len(history.EventTrades), testToken)
+	}
+}
@@ -0,0 +1,329 @@
+package evmrpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"pnl-scan-tool/package/utils"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"strings"
+)
+
+// Event topics.
+const (
+	transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	swapV2Topic   = "0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822"
+	swapV3Topic   = "0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67"
+)
+
+// Function selectors.
+const (
+	token0Selector   = "0x0dfe1681"
+	token1Selector   = "0xd21220a7"
+	decimalsSelector = "0x313ce567"
+	reservesSelector = "0x0902f1ac"
+)
+
+const (
+	zeroAddress = "0x0000000000000000000000000000000000000000"
+	deadAddress = "0x000000000000000000000000000000000000dead"
+
+	// dustToken is the smallest token change that counts at all.
+	dustToken = 1e-12
+)
+
+// TokenEvent is the change of one token in a wallet made by a transaction.
+type TokenEvent struct {
+	Token      string
+	Program    string
+	EventTrade pnlmodel.EventTrade
+}
+
+// poolSwap is what one Swap log moved into a pool, per token. Negative
+// amounts left the pool.
+type poolSwap struct {
+	program string
+	tokens  [2]string
+	amounts [2]*big.Int
+}
+
+// DecodeReceipt reconstructs what a transaction did to the tokens of wallet.
+//
+// The token side of a trade is the wallet's net ERC-20 transfers. The quote
+// side is read from the Uniswap V2 or V3 Swap logs of pools that pair the
+// token with the wrapped native token or a stablecoin, so that trades routed
+// through the native coin are priced too. Token changes without such a swap
+// are transfers, mints or burns. Multi-hop swaps through other tokens are
+// skipped.
+func (s *Source) DecodeReceipt(ctx context.Context, receipt *Receipt, wallet string, timestamp int64) []TokenEvent {
+	if receipt == nil || receipt.Status == "0x0" {
+		return nil
+	}
+
+	wallet = strings.ToLower(wallet)
+
+	deltas := map[string]*big.Int{}
+	counterparties := map[string]string{}
+	var tokens []string
+
+	var swaps []poolSwap
+
+	for _, log := range receipt.Logs {
+		if len(log.Topics) == 0 || log.Removed {
+			continue
+		}
+
+		switch log.Topics[0] {
+		case transferTopic:
+			// ERC-721 transfers index the token id as a fourth topic
+			if len(log.Topics) != 3 {
+				continue
+			}
+
+			token := strings.ToLower(log.Address)
+			from := topicAddress(log.Topics[1])
+			to := topicAddress(log.Topics[2])
+
+			if from != wallet && to != wallet {
+				continue
+			}
+
+			if _, seen := deltas[token]; !seen {
+				deltas[token] = new(big.Int)
+				tokens = append(tokens, token)
+			}
+
+			value := word(log.Data, 0)
+
+			if to == wallet {
+				deltas[token].Add(deltas[token], value)
+				counterparties[token] = from
+			}
+
+			if from == wallet {
+				deltas[token].Sub(deltas[token], value)
+				counterparties[token] = to
+			}
+		case swapV2Topic:
+			pair, ok := s.poolTokens(ctx, log.Address)
+			if !ok {
+				continue
+			}
+
+			// Swap(sender, amount0In, amount1In, amount0Out, amount1Out, to)
+			swaps = append(swaps, poolSwap{
+				program: "uniswap-v2",
+				tokens:  pair,
+				amounts: [2]*big.Int{
+					new(big.Int).Sub(word(log.Data, 0), word(log.Data, 2)),
+					new(big.Int).Sub(word(log.Data, 1), word(log.Data, 3)),
+				},
+			})
+		case swapV3Topic:
+			pair, ok := s.poolTokens(ctx, log.Address)
+			if !ok {
+				continue
+			}
+
+			// Swap(sender, recipient, amount0, amount1, sqrtPriceX96, liquidity, tick)
+			swaps = append(swaps, poolSwap{
+				program: "uniswap-v3",
+				tokens:  pair,
+				amounts: [2]*big.Int{signedWord(log.Data, 0), signedWord(log.Data, 1)},
+			})
+		}
+	}
+
+	var fee float64
+
+	if strings.EqualFold(receipt.From, wallet) {
+		gas := new(big.Int).Mul(hexToBig(receipt.GasUsed), hexToBig(receipt.EffectiveGasPrice))
+		fee = toFloat(gas, 18)
+	}
+
+	// Native price is only looked up once the transaction has a trade
+	nativePrice := -1.0
+
+	var events []TokenEvent
+
+	for _, token := range tokens {
+		// Stablecoins are kept, they are traded against the native coin and
+		// against each other
+		if token == s.WrappedNative {
+			continue
+		}
+
+		delta := toFloat(deltas[token], s.decimals(ctx, token))
+
+		if math.Abs(delta) <= dustToken {
+			continue
+		}
+
+		eventTrade := pnlmodel.EventTrade{
+			TxHash:       receipt.TransactionHash,
+			TokensAmount: math.Abs(delta),
+			Timestamp:    timestamp,
+			DateTime:     utils.ConvertTimestampToDate(timestamp),
+		}
+
+		quoteToken, quoteRaw, program, routed := s.quoteLeg(swaps, token)
+
+		switch {
+		case quoteToken != "":
+			if nativePrice < 0 {
+				nativePrice = s.nativePriceUSD(ctx, hexToUint64(receipt.BlockNumber))
+			}
+
+			quote := math.Abs(toFloat(quoteRaw, s.decimals(ctx, quoteToken)))
+
+			if quoteToken == s.WrappedNative {
+				eventTrade.QuoteAmount = quote
+				eventTrade.QuoteAmountUSD = quote * nativePrice
+			} else {
+				// Stablecoin trades are converted to the native coin
+				if nativePrice == 0 {
+					continue
+				}
+
+				eventTrade.QuoteAmountUSD = quote
+				eventTrade.QuoteAmount = quote / nativePrice
+			}
+
+			if delta > 0 {
+				eventTrade.EventType = pnlmodel.EventBuy
+			} else {
+				eventTrade.EventType = pnlmodel.EventSell
+			}
+
+			eventTrade.Price = eventTrade.QuoteAmount / eventTrade.TokensAmount
+			eventTrade.PriceUSD = eventTrade.QuoteAmountUSD / eventTrade.TokensAmount
+		case routed:
+			// Swapped against another token, which cannot be priced here
+			continue
+		default:
+			program = ""
+			eventTrade.EventType = transferType(counterparties[token], delta)
+
+			if eventTrade.EventType == pnlmodel.EventTransferIn || eventTrade.EventType == pnlmodel.EventTransferOut {
+				eventTrade.Counterparty = counterparties[token]
+			}
+		}
+
+		events = append(events, TokenEvent{Token: token, Program: program, EventTrade: eventTrade})
+	}
+
+	if len(events) > 0 {
+		events[0].EventTrade.Fee = fee
+	}
+
+	return events
+}
+
+// quoteLeg sums what the swaps pairing token with a quote token moved of the
+// quote token. routed reports whether token was swapped at all.
+func (s *Source) quoteLeg(swaps []poolSwap, token string) (string, *big.Int, string, bool) {
+	quoteToken := ""
+	program := ""
+	quoteRaw := new(big.Int)
+	routed := false
+
+	for _, swap := range swaps {
+		for side := 0; side < 2; side++ {
+			if swap.tokens[side] != token {
+				continue
+			}
+
+			routed = true
+			other := swap.tokens[1-side]
+
+			if !s.isQuote(other) || (quoteToken != "" && other != quoteToken) {
+				continue
+			}
+
+			quoteToken = other
+			program = swap.program
+			quoteRaw.Add(quoteRaw, swap.amounts[1-side])
+		}
+	}
+
+	return quoteToken, quoteRaw, program, routed
+}
+
+func (s *Source) isQuote(token string) bool {
+	if token == s.WrappedNative {
+		return true
+	}
+
+	for _, stablecoin := range s.Stablecoins {
+		if token == stablecoin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nativePriceUSD returns the price of the native coin at block, from the
+// reserves of NativeUSDPool. It is 0 when the pool cannot be read at that
+// block, e.g. before it was created or from a node without archive state.
+func (s *Source) nativePriceUSD(ctx context.Context, block uint64) float64 {
+	if s.NativeUSDPool == "" {
+		return 0
+	}
+
+	s.mu.Lock()
+	price, ok := s.nativePrices[block]
+	s.mu.Unlock()
+
+	if ok {
+		return price
+	}
+
+	pair, ok := s.poolTokens(ctx, s.NativeUSDPool)
+
+	if !ok {
+		return 0
+	}
+
+	reserves, err := s.Client.CallAt(ctx, s.NativeUSDPool, reservesSelector, block)
+
+	if err != nil {
+		fmt.Println("Error pricing native coin:", err)
+		return 0
+	}
+
+	native, stable := 0, 1
+
+	if pair[1] == s.WrappedNative {
+		native, stable = 1, 0
+	}
+
+	nativeReserve := toFloat(word(reserves, native), s.decimals(ctx, pair[native]))
+
+	if nativeReserve != 0 {
+		price = toFloat(word(reserves, stable), s.decimals(ctx, pair[stable])) / nativeReserve
+	}
+
+	s.mu.Lock()
+	s.nativePrices[block] = price
+	s.mu.Unlock()
+
+	return price
+}
+
+// transferType classifies a token change that is not a swap.
+func transferType(counterparty string, delta float64) string {
+	if delta > 0 {
+		if counterparty == zeroAddress {
+			return pnlmodel.EventMint
+		}
+		return pnlmodel.EventTransferIn
+	}
+
+	if counterparty == zeroAddress || counterparty == deadAddress {
+		return pnlmodel.EventBurn
+	}
+
+	return pnlmodel.EventTransferOut
+}
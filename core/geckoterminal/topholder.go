@@ -1,15 +1,16 @@
 package geckoterminal
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
+	"pnl-scan-tool/package/transport"
 	"pnl-scan-tool/package/utils"
-	"time"
-
-	"golang.org/x/exp/rand"
 )
 
 // TokenHoldersData is the root structure containing the token holders details.
@@ -38,29 +39,39 @@ type Holder struct {
 	IsLiquidityPool *bool   `json:"is_liquidity_pool"` // Use pointer to allow null values
 }
 
-func TopHolders(chain string, token string) (TokenHoldersData, error) {
+func TopHolders(ctx context.Context, chain string, token string) (TokenHoldersData, error) {
 
 	apiUrl := fmt.Sprintf("https://app.geckoterminal.com/api/p1/%s/tokens/%s/top_holders", chain, token)
 
-	data, err := fetchDataGeckoTerminalFromAPI(apiUrl)
+	data, cached := cache.Get(ctx, cache.Holders, apiUrl)
 
-	if err != nil {
-		return TokenHoldersData{}, err
+	if !cached {
+		var err error
+
+		data, err = fetchDataGeckoTerminalFromAPI(ctx, apiUrl)
+
+		if err != nil {
+			return TokenHoldersData{}, err
+		}
 	}
 
 	var tokenHoldersData TokenHoldersData
 
-	err = json.Unmarshal(data, &tokenHoldersData)
+	err := json.Unmarshal(data, &tokenHoldersData)
 
 	if err != nil {
 		return TokenHoldersData{}, err
 	}
 
+	if !cached {
+		cache.Set(ctx, cache.Holders, apiUrl, data)
+	}
+
 	return tokenHoldersData, nil
 }
 
-func fetchDataGeckoTerminalFromAPI(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func fetchDataGeckoTerminalFromAPI(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
@@ -70,66 +81,36 @@ func fetchDataGeckoTerminalFromAPI(url string) ([]byte, error) {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
-	client := &http.Client{Transport: tr}
-
-	var body []byte
+	client := &http.Client{Transport: transport.New(tr)}
 
-	maxRetries := 100
+	// Set a random User-Agent
+	req.Header.Set("User-Agent", utils.GetRandomUserAgent())
 
-	for retries := 1; retries <= maxRetries; retries++ {
-		// Set a random User-Agent
-		req.Header.Set("User-Agent", utils.GetRandomUserAgent())
-
-		req.Header.Set("Referer", url)
-
-		// Set random headers
-		headers := utils.GetRandomHeaders()
-		for key, value := range headers {
-			req.Header.Set(key, value)
-		}
+	req.Header.Set("Referer", url)
 
-		// // Set random cookies
-		// cookies := utils.GetRandomCookies()
-
-		// for key, value := range cookies {
-		// 	req.AddCookie(&http.Cookie{Name: key, Value: value})
-		// }
-
-		resp, err := client.Do(req)
+	// Set random headers
+	headers := utils.GetRandomHeaders()
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch data: %v", err)
-		}
+	resp, err := client.Do(req)
 
-		defer resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data: %w", err)
+	}
 
-		if resp.StatusCode == http.StatusOK {
-			body, err = io.ReadAll(resp.Body)
+	defer resp.Body.Close()
 
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
-			}
-			return body, nil
-		}
+	if resp.StatusCode != http.StatusOK {
+		return nil, source.StatusError(resp.StatusCode)
+	}
 
-		// Handle Too Many Requests (429) or Forbidden (403) with dynamic backoff
-		if resp.StatusCode == 429 || resp.StatusCode == 403 {
-			// Exponential backoff with jitter
-			baseWaitTime := time.Duration(2<<retries) * time.Second
-			jitter := time.Duration(rand.Intn(1000)) * time.Millisecond
-			totalWaitTime := baseWaitTime + jitter
-			fmt.Printf("Received %d, retrying in %v...\n", resp.StatusCode, totalWaitTime)
-			time.Sleep(totalWaitTime)
-			// proxyURL = proxy.GetRandomProxy()
-			// tr.Proxy = http.ProxyURL(proxyURL)
-			continue
-		}
+	body, err := io.ReadAll(resp.Body)
 
-		// Handle other non-successful responses
-		respBody, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Failed with status %d, body: %s\n", resp.StatusCode, respBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
 	}
 
-	return nil, fmt.Errorf("exceeded maximum retries")
-
+	return body, nil
 }
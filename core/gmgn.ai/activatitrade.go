@@ -1,77 +1,107 @@
 package gmgnai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
 	gmaimodel "pnl-scan-tool/src/model/gmai.model"
+	"time"
 )
 
-// Function to get wallet activities with retry and pagination
-func getWalletActivities(chain string, wallet string, cursor string) (*gmaimodel.ApiResponseGMGNAI, error) {
+// Function to get a page of wallet activities
+func getWalletActivities(ctx context.Context, chain string, wallet string, cursor string) (*gmaimodel.ApiResponseGMGNAI, error) {
 	url := fmt.Sprintf("%s%s?type=buy&type=sell&wallet=%s&limit=%d", baseUrl, chain, wallet, limit)
 	fmt.Println(url)
 	if cursor != "" {
 		url += "&cursor=" + cursor
 	}
 
-	// Fetch with retry logic
-	result, err := fetchWithRetry(url)
+	var result []byte
+	cached := false
 
-	if err != nil {
-		return nil, err
+	// Pages behind a cursor keep their trades once they settled. The first
+	// page is always fetched to pick up new trades
+	if cursor != "" {
+		result, cached = cache.Get(ctx, cache.History, url)
+	}
+
+	if !cached {
+		var err error
+
+		// utils.Client retries the request while gmgn rate limits it
+		result, err = fetch(ctx, url)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Parse JSON response
 	var apiResponse gmaimodel.ApiResponseGMGNAI
 
 	if err := json.Unmarshal(result, &apiResponse); err != nil {
-		return nil, err
+		return nil, source.SchemaError(err)
+	}
+
+	if !cached && cursor != "" && settled(apiResponse.Data.Activities) {
+		cache.Set(ctx, cache.History, url, result)
 	}
 
 	return &apiResponse, nil
 }
 
-func ActivityAllTrade(chain string, wallet string, scanDay int) []gmaimodel.Activity {
+// ActivityAllTrade lists the latest activity of every token a wallet traded,
+// newest first. A non zero days keeps the tokens traded in the last days
+// days.
+func ActivityAllTrade(ctx context.Context, chain string, wallet string, days int) ([]gmaimodel.Activity, error) {
 	var allActivities []gmaimodel.Activity
+	var since int64
+
+	if days != 0 {
+		since = time.Now().AddDate(0, 0, -days).Unix()
+	}
+
 	cursor := ""
 	// count := 0
 	for {
-		apiResponse, err := getWalletActivities(chain, wallet, cursor)
+		apiResponse, err := getWalletActivities(ctx, chain, wallet, cursor)
 
 		if err != nil {
-			log.Fatalf("Error fetching data: %v", err)
+			return nil, err
 		}
 
 		// count += len(apiResponse.Data.Activities)
 
 		// fmt.Println("Scan Token Trade: ", count)
 
-		// Append activities to the slice
-		allActivities = append(allActivities, apiResponse.Data.Activities...)
+		// Activities come newest first, the first one out of the window
+		// ends it
+		older := false
 
-		allActivities = RemoveDuplicates(allActivities)
-
-		fmt.Println("Scan Token Trade: ", len(allActivities))
+		for _, activity := range apiResponse.Data.Activities {
+			if activity.Timestamp < since {
+				older = true
+				break
+			}
 
-		if len(allActivities) > scanDay && scanDay != 0 {
-			allActivities = allActivities[:scanDay]
-			break
+			allActivities = append(allActivities, activity)
 		}
 
-		// if len(allActivities) > 300 {
-		// 	break
-		// }
+		allActivities = RemoveDuplicates(allActivities)
+
+		fmt.Println("Scan Token Trade: ", len(allActivities))
 
 		// If there's no next cursor, break the loop (end of data)
-		if apiResponse.Data.Next == "" {
+		if older || apiResponse.Data.Next == "" {
 			break
 		}
 
 		cursor = apiResponse.Data.Next
 	}
 
-	return RemoveDuplicates(allActivities)
+	return allActivities, nil
 }
 
 func RemoveDuplicates(Activitys []gmaimodel.Activity) []gmaimodel.Activity {
@@ -1,65 +1,96 @@
 package gmgnai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
 	gmaimodel "pnl-scan-tool/src/model/gmai.model"
 )
 
-// Function to get wallet activities with retry and pagination
-func getWalletActivitiesToken(chain string, wallet string, token string, cursor string) (*gmaimodel.ApiResponseGMGNAI, error) {
-	url := fmt.Sprintf("%s%s?type=buy&type=sell&wallet=%s&limit=%d&token=%s", baseUrl, chain, wallet, limit, token)
+// Function to get a page of wallet activities. Transfers are
+// included so that tokens received or sent without a swap can be accounted.
+func getWalletActivitiesToken(ctx context.Context, chain string, wallet string, token string, cursor string) (*gmaimodel.ApiResponseGMGNAI, error) {
+	url := fmt.Sprintf("%s%s?type=buy&type=sell&type=transfer&wallet=%s&limit=%d&token=%s", baseUrl, chain, wallet, limit, token)
 	if cursor != "" {
 		url += "&cursor=" + cursor
 	}
 
-	// Fetch with retry logic
-	result, err := fetchWithRetry(url)
+	var result []byte
+	cached := false
 
-	if err != nil {
-		return nil, err
+	// Pages behind a cursor keep their trades once they settled. The first
+	// page is always fetched to pick up new trades
+	if cursor != "" {
+		result, cached = cache.Get(ctx, cache.History, url)
+	}
+
+	if !cached {
+		var err error
+
+		// utils.Client retries the request while gmgn rate limits it
+		result, err = fetch(ctx, url)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Parse JSON response
 	var apiResponse gmaimodel.ApiResponseGMGNAI
 
 	if err := json.Unmarshal(result, &apiResponse); err != nil {
-		return nil, err
+		return nil, source.SchemaError(err)
+	}
+
+	if !cached && cursor != "" && settled(apiResponse.Data.Activities) {
+		cache.Set(ctx, cache.History, url, result)
 	}
 
 	return &apiResponse, nil
 }
 
-func ActivityAllTradeToken(chain string, wallet string, token string) []gmaimodel.Activity {
+// ActivityAllTradeToken pages through the activities of a wallet on one
+// token, newest first, down to since, a Unix timestamp. 0 reads them all. It
+// stops at MaxActivities when set and then reports the history as truncated.
+func ActivityAllTradeToken(ctx context.Context, chain string, wallet string, token string, since int64) ([]gmaimodel.Activity, bool, error) {
 	var allActivities []gmaimodel.Activity
 	cursor := ""
-	count := 0
 	for {
-		apiResponse, err := getWalletActivitiesToken(chain, wallet, token, cursor)
+		apiResponse, err := getWalletActivitiesToken(ctx, chain, wallet, token, cursor)
 
 		if err != nil {
-			log.Fatalf("Error fetching data: %v", err)
+			return nil, false, err
 		}
 
-		count += len(apiResponse.Data.Activities)
+		reachedSince := false
 
-		if count >= 51 {
-			break
+		// Append activities to the slice
+		for _, activity := range apiResponse.Data.Activities {
+			if activity.Timestamp < since {
+				reachedSince = true
+				break
+			}
+
+			allActivities = append(allActivities, activity)
 		}
 
-		fmt.Println("Scan Total Event Trade: ", count)
+		fmt.Println("Scan Total Event Trade: ", len(allActivities))
 
-		// Append activities to the slice
-		allActivities = append(allActivities, apiResponse.Data.Activities...)
+		if MaxActivities != 0 && len(allActivities) >= MaxActivities {
+			truncated := len(allActivities) > MaxActivities || (apiResponse.Data.Next != "" && !reachedSince)
+
+			return allActivities[:MaxActivities], truncated, nil
+		}
 
 		// If there's no next cursor, break the loop (end of data)
-		if apiResponse.Data.Next == "" {
+		if apiResponse.Data.Next == "" || reachedSince {
 			break
 		}
 
 		cursor = apiResponse.Data.Next
 	}
 
-	return allActivities
+	return allActivities, false, nil
 }
@@ -1,25 +1,28 @@
 package gmgnai
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
 	"pnl-scan-tool/package/utils"
-	"time"
-
-	"golang.org/x/exp/rand"
+	gmaimodel "pnl-scan-tool/src/model/gmai.model"
 )
 
 const baseUrl = "https://gmgn.ai/defi/quotation/v1/wallet_activity/"
 const limit = 100 // Maximize limit per request
 
-const maxRetries = 1000 // Maximum retry attempts
+// MaxActivities caps the activities read for one token of a wallet. 0 reads
+// them all.
+var MaxActivities = 0
 
-// Fetch data from the API with retries and exponential backoff
-func fetchWithRetry(url string) ([]byte, error) {
+// Fetch data from the API, retries and pacing are left to utils.Client
+func fetch(ctx context.Context, url string) ([]byte, error) {
 	// Create a new request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -31,86 +34,41 @@ func fetchWithRetry(url string) ([]byte, error) {
 		return nil, err
 	}
 
-	var body []byte
-	// var err error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-
-		// Set headers to mimic a real browser request
-		req.Header.Set("accept", "application/json")
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36")
-		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-		req.Header.Set("Referer", "https://gmgn.ai/") // Set referer to appear like a browser visit
-		req.Header.Set("Connection", "keep-alive")
-
-		// // Set cookies
-		// cookie := &http.Cookie{
-		// 	Name:  "__cf_bm",
-		// 	Value: "du18h5EJkRmsW3pbyydYrlKcCS5KnyQL_v1eBrq_aiU-1728406295-1.0.1.1-Hu4la6GZPD4iYPr8xFM2G_2MWhfxqCH4H2Oc.7hISuOOAnDtKF9qE7q6gGwLCdGgWRZP_B5IchEdvpaT2CDLug",
-		// 	Path:  "/",
-		// }
-
-		// req.AddCookie(cookie)
-
-		// Add cookies to the request
-		for name, value := range config.Cookies {
-			req.AddCookie(&http.Cookie{
-				Name:  name,
-				Value: value,
-			})
-		}
-
-		// Make the request
-		resp, err := utils.Client.Do(req)
-
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch data: %v", err)
-		}
-
-		defer resp.Body.Close()
-
-		if resp.StatusCode == http.StatusOK {
-
-			body, err = io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
-			}
-			return body, nil
-		}
-
-		// Handle Too Many Requests (429) or Forbidden (403) with dynamic backoff
-		if resp.StatusCode == 429 || resp.StatusCode == 403 {
-			// Exponential backoff with jitter
-			baseWaitTime := time.Duration(2<<attempt) * time.Second
-			jitter := time.Duration(rand.Intn(1000)) * time.Millisecond
-			totalWaitTime := baseWaitTime + jitter
-			fmt.Printf("Received %d, retrying in %v...\n", resp.StatusCode, totalWaitTime)
-			fmt.Println("Respone:", resp.Status)
-			// time.Sleep(totalWaitTime)
-
-			// respBody, err := ByPass(url)
-
-			// // fmt.Println("Request:", url)
-
-			// if err != nil {
-			// 	continue
-			// }
+	// Set headers to mimic a real browser request
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Referer", "https://gmgn.ai/") // Set referer to appear like a browser visit
+	req.Header.Set("Connection", "keep-alive")
+
+	// Add cookies to the request
+	for name, value := range config.Cookies {
+		req.AddCookie(&http.Cookie{
+			Name:  name,
+			Value: value,
+		})
+	}
 
-			// return respBody, err
+	// Make the request
+	resp, err := utils.Client.Do(req)
 
-			// time.Sleep(1 * time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data: %w", err)
+	}
 
-			// proxyURL = proxy.GetRandomProxy()
-			// tr.Proxy = http.ProxyURL(proxyURL)
+	defer resp.Body.Close()
 
-			continue
-		}
+	if resp.StatusCode != http.StatusOK {
+		// Still throttled after the retries, or 403 when the cookies expired
+		return nil, source.StatusError(resp.StatusCode)
+	}
 
-		// Handle other non-successful responses
-		respBody, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Failed with status %d, body: %s\n", resp.StatusCode, respBody)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
 	}
 
-	return nil, err
+	return body, nil
 }
 
 func ByPass(urls string) ([]byte, error) {
@@ -143,3 +101,21 @@ func ByPass(urls string) ([]byte, error) {
 	return body, nil
 
 }
+
+// settled reports whether every activity of a page is too old to change. gmgn
+// cursors point at the last activity returned, so later trades don't shift
+// such a page. It can only change if gmgn corrects past activities, which the
+// History TTL bounds.
+func settled(activities []gmaimodel.Activity) bool {
+	if len(activities) == 0 {
+		return false
+	}
+
+	for _, activity := range activities {
+		if !cache.IsSettled(activity.Timestamp) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,282 @@
+package gmgnai
+
+import (
+	"context"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/chains"
+	"pnl-scan-tool/package/utils"
+	gmaimodel "pnl-scan-tool/src/model/gmai.model"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"strings"
+)
+
+// burnAddresses receive tokens that are destroyed.
+var burnAddresses = []string{
+	"1nc1nerator11111111111111111111111111111111",
+	"0x0000000000000000000000000000000000000000",
+	"0x000000000000000000000000000000000000dead",
+}
+
+// Source serves trade data from gmgn.ai.
+type Source struct {
+	// NativePriceUSD prices the native coin of a chain in USD. It is only
+	// needed for swaps between stablecoins with no native trade to take the
+	// rate from.
+	NativePriceUSD func(ctx context.Context, chainID string) (float64, error)
+}
+
+func (Source) Name() string {
+	return "gmgn"
+}
+
+func (Source) TradedTokens(ctx context.Context, chainID string, wallet string, days int) ([]source.Token, error) {
+	chain, ok := gmgnChain(chainID)
+
+	if !ok {
+		return nil, source.ErrNotSupported
+	}
+
+	activities, err := ActivityAllTrade(ctx, chain.GMGNID, wallet, days)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []source.Token
+
+	for _, activity := range activities {
+		tokens = append(tokens, source.Token{
+			Address: activity.TokenAddress,
+			Symbol:  activity.Token.Symbol,
+		})
+	}
+
+	return tokens, nil
+}
+
+func (s Source) Trades(ctx context.Context, chainID string, wallet string, token string) (pnlmodel.TradeHistory, error) {
+	return s.TradesSince(ctx, chainID, wallet, token, 0)
+}
+
+// TradesSince stops paging at the first activity older than since.
+func (s Source) TradesSince(ctx context.Context, chainID string, wallet string, token string, since int64) (pnlmodel.TradeHistory, error) {
+	chain, ok := gmgnChain(chainID)
+
+	if !ok {
+		return pnlmodel.TradeHistory{}, source.ErrNotSupported
+	}
+
+	history := pnlmodel.TradeHistory{TokenAddress: token}
+
+	activities, truncated, err := ActivityAllTradeToken(ctx, chain.GMGNID, wallet, token, since)
+
+	if err != nil {
+		return pnlmodel.TradeHistory{}, err
+	}
+
+	history.Truncated = truncated
+
+	if len(activities) != 0 {
+		history.Watermark = activities[0].Timestamp
+	}
+
+	// Swaps between two stablecoins are quoted in a stablecoin and are
+	// converted to the native coin
+	isStable := chain.IsStablecoin(token)
+
+	last := -1
+
+	// gmgn.ai returns the newest activity first
+	for i := len(activities) - 1; i >= 0; i-- {
+		activity := activities[i]
+
+		eventTrade := eventTradeFromActivity(wallet, activity)
+
+		isSwap := eventTrade.EventType == pnlmodel.EventBuy || eventTrade.EventType == pnlmodel.EventSell
+		isNative := chain.IsQuote(activity.QuoteAddress)
+
+		if isSwap && !isNative && !(isStable && chain.IsStablecoin(activity.QuoteAddress)) {
+			continue
+		}
+
+		if isSwap && !isNative {
+			nativeUSD := s.nativeUSD(ctx, chain, activities, i)
+
+			if nativeUSD == 0 {
+				continue
+			}
+
+			eventTrade.Price = eventTrade.PriceUSD / nativeUSD
+			eventTrade.QuoteAmount = eventTrade.QuoteAmountUSD / nativeUSD
+		}
+
+		if isSwap || activity.PriceUSD != 0 {
+			last = i
+		}
+
+		history.TokenSymbol = activity.Token.Symbol
+		history.EventTrades = append(history.EventTrades, eventTrade)
+	}
+
+	if last >= 0 {
+		history.MarkPriceUSD = activities[last].Token.Price
+
+		if nativeUSD := s.nativeUSD(ctx, chain, activities, last); nativeUSD != 0 {
+			history.MarkPriceQuote = history.MarkPriceUSD / nativeUSD
+		}
+	}
+
+	return history, nil
+}
+
+// nativeUSD returns the USD price of the native coin at the time of
+// activities[index], from the native quoted activity closest to it, or the
+// current price when there is none.
+func (s Source) nativeUSD(ctx context.Context, chain chains.Chain, activities []gmaimodel.Activity, index int) float64 {
+	for distance := 0; distance < len(activities); distance++ {
+		for _, i := range []int{index - distance, index + distance} {
+			if i < 0 || i >= len(activities) {
+				continue
+			}
+
+			activity := activities[i]
+
+			if chain.IsQuote(activity.QuoteAddress) && activity.Price != 0 && activity.PriceUSD != 0 {
+				return activity.PriceUSD / activity.Price
+			}
+		}
+	}
+
+	if s.NativePriceUSD == nil {
+		return 0
+	}
+
+	price, err := s.NativePriceUSD(ctx, chain.ID)
+
+	if err != nil {
+		return 0
+	}
+
+	return price
+}
+
+func (Source) TopTraders(ctx context.Context, chainID string, token string) ([]string, error) {
+	chain, ok := gmgnChain(chainID)
+
+	if !ok {
+		return nil, source.ErrNotSupported
+	}
+
+	traders, err := TopTradersToken(ctx, chain.GMGNID, token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return walletAddresses(traders), nil
+}
+
+func (Source) TopHolders(ctx context.Context, chainID string, token string) ([]string, error) {
+	chain, ok := gmgnChain(chainID)
+
+	if !ok {
+		return nil, source.ErrNotSupported
+	}
+
+	holders, err := TopHoldersToken(ctx, chain.GMGNID, token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return walletAddresses(holders), nil
+}
+
+// Price is not served by gmgn.ai on its own; prices come with the trades.
+func (Source) Price(ctx context.Context, chain string, token string) (source.Price, error) {
+	return source.Price{}, source.ErrNotSupported
+}
+
+// gmgnChain returns the registered chain with id if gmgn.ai covers it.
+func gmgnChain(id string) (chains.Chain, bool) {
+	chain, err := chains.Get(id)
+
+	if err != nil || chain.GMGNID == "" {
+		return chains.Chain{}, false
+	}
+
+	return chain, true
+}
+
+// eventTradeFromActivity normalizes a gmgn.ai activity of wallet. gmgn.ai
+// does not report network fees, so Fee is left at zero.
+func eventTradeFromActivity(wallet string, activity gmaimodel.Activity) pnlmodel.EventTrade {
+	eventType, counterparty := activityEventType(wallet, activity)
+
+	return pnlmodel.EventTrade{
+		TxHash:         activity.TxHash,
+		EventType:      eventType,
+		Counterparty:   counterparty,
+		Price:          activity.Price,
+		PriceUSD:       activity.PriceUSD,
+		QuoteAmount:    utils.ConvertStringToFloat64(activity.QuoteAmount),
+		QuoteAmountUSD: activity.CostUSD,
+		TokensAmount:   utils.ConvertStringToFloat64(activity.TokenAmount),
+		Timestamp:      activity.Timestamp,
+		DateTime:       utils.ConvertTimestampToDate(activity.Timestamp),
+	}
+}
+
+// activityEventType classifies a gmgn.ai transfer as an inflow or outflow of
+// wallet and returns the other side of it. Swaps are returned as is.
+func activityEventType(wallet string, activity gmaimodel.Activity) (string, string) {
+	if activity.EventType != "transfer" {
+		return activity.EventType, ""
+	}
+
+	from := stringValue(activity.FromAddress)
+	to := stringValue(activity.ToAddress)
+
+	if strings.EqualFold(to, wallet) {
+		switch {
+		case from == "":
+			return pnlmodel.EventMint, ""
+		case activity.FromIsContract != nil && *activity.FromIsContract:
+			return pnlmodel.EventAirdrop, from
+		}
+
+		return pnlmodel.EventTransferIn, from
+	}
+
+	if to == "" || isBurnAddress(to) {
+		return pnlmodel.EventBurn, ""
+	}
+
+	return pnlmodel.EventTransferOut, to
+}
+
+func walletAddresses(wallets []WalletData) []string {
+	addresses := make([]string, 0, len(wallets))
+	for _, wallet := range wallets {
+		addresses = append(addresses, wallet.Address)
+	}
+	return addresses
+}
+
+func isBurnAddress(address string) bool {
+	for _, burnAddress := range burnAddresses {
+		if strings.EqualFold(burnAddress, address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
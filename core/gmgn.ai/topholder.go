@@ -1,9 +1,11 @@
 package gmgnai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
 )
 
 type TagRank struct {
@@ -59,38 +61,48 @@ type TopHoldersData struct {
 
 const baseUrlTopHolder = "https://gmgn.ai/defi/quotation/v1/tokens/top_holders/"
 
-func getTopHoldersToken(chain string, token string) (*TopHoldersData, error) {
+func getTopHoldersToken(ctx context.Context, chain string, token string) (*TopHoldersData, error) {
 	url := fmt.Sprintf("%s/%s/%s?limit=%d&tag=All&orderby=amount_percentage&direction=desc", baseUrlTopHolder, chain, token, limit)
 	// if cursor != "" {
 	// 	url += "&cursor=" + cursor
 	// }
 
-	// Fetch with retry logic
-	result, err := fetchWithRetry(url)
+	result, cached := cache.Get(ctx, cache.Holders, url)
 
-	if err != nil {
-		return nil, err
+	if !cached {
+		var err error
+
+		// utils.Client retries the request while gmgn rate limits it
+		result, err = fetch(ctx, url)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Parse JSON response
 	var apiResponse TopHoldersData
 
 	if err := json.Unmarshal(result, &apiResponse); err != nil {
-		return nil, err
+		return nil, source.SchemaError(err)
+	}
+
+	if !cached {
+		cache.Set(ctx, cache.Holders, url, result)
 	}
 
 	return &apiResponse, nil
 }
 
-func TopHoldersToken(chain string, token string) []WalletData {
+func TopHoldersToken(ctx context.Context, chain string, token string) ([]WalletData, error) {
 	var TopTraders []WalletData
 
 	count := 0
 
-	apiResponse, err := getTopHoldersToken(chain, token)
+	apiResponse, err := getTopHoldersToken(ctx, chain, token)
 
 	if err != nil {
-		log.Fatalf("Error fetching data: %v", err)
+		return nil, err
 	}
 
 	count += len(apiResponse.Data)
@@ -100,5 +112,5 @@ func TopHoldersToken(chain string, token string) []WalletData {
 	// Append activities to the slice
 	TopTraders = append(TopTraders, apiResponse.Data...)
 
-	return TopTraders
+	return TopTraders, nil
 }
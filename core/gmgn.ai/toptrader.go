@@ -1,9 +1,11 @@
 package gmgnai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
 )
 
 type TopTradersData struct {
@@ -14,11 +16,11 @@ type TopTradersData struct {
 
 const baseUrlTrader = "https://gmgn.ai/defi/quotation/v1/tokens/top_traders"
 
-func getTopTradersToken(chain string, token string) (*TopHoldersData, error) {
+func getTopTradersToken(ctx context.Context, chain string, token string) (*TopHoldersData, error) {
 	var url string
 	if chain == "sol" {
 		url = fmt.Sprintf("%s/%s/%s?limit=%d&tag=All&orderby=realized_profit&direction=desc", baseUrlTrader, chain, token, limit)
-	} else if chain == "eth" {
+	} else {
 		url = fmt.Sprintf("%s/%s/%s?orderby=realized_profit&direction=desc", baseUrlTrader, chain, token)
 	}
 
@@ -28,32 +30,42 @@ func getTopTradersToken(chain string, token string) (*TopHoldersData, error) {
 	// 	url += "&cursor=" + cursor
 	// }
 
-	// Fetch with retry logic
-	result, err := fetchWithRetry(url)
+	result, cached := cache.Get(ctx, cache.Holders, url)
 
-	if err != nil {
-		return nil, err
+	if !cached {
+		var err error
+
+		// utils.Client retries the request while gmgn rate limits it
+		result, err = fetch(ctx, url)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Parse JSON response
 	var apiResponse TopHoldersData
 
 	if err := json.Unmarshal(result, &apiResponse); err != nil {
-		return nil, err
+		return nil, source.SchemaError(err)
+	}
+
+	if !cached {
+		cache.Set(ctx, cache.Holders, url, result)
 	}
 
 	return &apiResponse, nil
 }
 
-func TopTradersToken(chain string, token string) []WalletData {
+func TopTradersToken(ctx context.Context, chain string, token string) ([]WalletData, error) {
 	var TopTraders []WalletData
 
 	count := 0
 
-	apiResponse, err := getTopTradersToken(chain, token)
+	apiResponse, err := getTopTradersToken(ctx, chain, token)
 
 	if err != nil {
-		log.Fatalf("Error fetching data: %v", err)
+		return nil, err
 	}
 
 	count += len(apiResponse.Data)
@@ -63,5 +75,5 @@ func TopTradersToken(chain string, token string) []WalletData {
 	// Append activities to the slice
 	TopTraders = append(TopTraders, apiResponse.Data...)
 
-	return TopTraders
+	return TopTraders, nil
 }
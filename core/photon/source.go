@@ -0,0 +1,134 @@
+package photon
+
+import (
+	"context"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/utils"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// Source serves Solana trade data from Photon.
+type Source struct{}
+
+func (Source) Name() string {
+	return "photon"
+}
+
+// TradedTokens is not served by Photon, which only lists trades per pool.
+func (Source) TradedTokens(ctx context.Context, chain string, wallet string, days int) ([]source.Token, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (Source) Trades(ctx context.Context, chain string, wallet string, tokenAddress string) (pnlmodel.TradeHistory, error) {
+	if chain != "sol" {
+		return pnlmodel.TradeHistory{}, source.ErrNotSupported
+	}
+
+	// Photon blocks clients that request too fast
+	if err := utils.Sleep(ctx, time.Duration(1000+rand.Intn(1000))*time.Millisecond); err != nil {
+		return pnlmodel.TradeHistory{}, err
+	}
+
+	token := Token{TokenAddress: tokenAddress}
+
+	data, err := token.TokenInfomation(ctx)
+
+	if err != nil {
+		return pnlmodel.TradeHistory{}, err
+	}
+
+	w := Wallet{WalletAddress: wallet}
+
+	transactions, err := w.Transactions(ctx, data.PoolId)
+
+	if err != nil {
+		return pnlmodel.TradeHistory{}, err
+	}
+
+	history := pnlmodel.TradeHistory{
+		TokenAddress:   tokenAddress,
+		TokenSymbol:    data.TokenSymbol,
+		MarkPriceQuote: data.PriceQuote,
+		MarkPriceUSD:   data.PriceUsd,
+	}
+
+	// Photon returns the newest event first
+	for i := len(transactions) - 1; i >= 0; i-- {
+		attributes := transactions[i].Attributes
+
+		// Photon reports the side in Type and the kind of event (swap,
+		// create_pool, ...) in EventType.
+		if attributes.EventType == "create_pool" {
+			continue
+		}
+
+		history.EventTrades = append(history.EventTrades, pnlmodel.EventTrade{
+			TxHash:         attributes.TxHash,
+			EventType:      attributes.Type,
+			Price:          utils.ConvertStringToFloat64(attributes.PriceQuote),
+			PriceUSD:       utils.ConvertStringToFloat64(attributes.PriceUsd),
+			QuoteAmount:    utils.ConvertStringToFloat64(attributes.QuoteAmount),
+			QuoteAmountUSD: utils.ConvertToFloat64(attributes.UsdAmount),
+			TokensAmount:   utils.ConvertStringToFloat64(attributes.TokensAmount),
+			Timestamp:      attributes.Timestamp,
+			DateTime:       utils.ConvertTimestampToDate(attributes.Timestamp),
+		})
+	}
+
+	return history, nil
+}
+
+func (Source) TopTraders(ctx context.Context, chain string, tokenAddress string) ([]string, error) {
+	if chain != "sol" {
+		return nil, source.ErrNotSupported
+	}
+
+	token := Token{TokenAddress: tokenAddress}
+
+	data, err := token.TokenInfomation(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	topTraders, err := token.TopTraders(ctx, data.PoolId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := make([]string, 0, len(topTraders))
+	for _, trader := range topTraders {
+		wallets = append(wallets, trader.Attributes.Signer)
+	}
+
+	return wallets, nil
+}
+
+// TopHolders is not served by Photon.
+func (Source) TopHolders(ctx context.Context, chain string, tokenAddress string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (Source) Price(ctx context.Context, chain string, tokenAddress string) (source.Price, error) {
+	if chain != "sol" {
+		return source.Price{}, source.ErrNotSupported
+	}
+
+	token := Token{TokenAddress: tokenAddress}
+
+	data, err := token.TokenInfomation(ctx)
+
+	if err != nil {
+		return source.Price{}, err
+	}
+
+	return source.Price{
+		Symbol:     data.TokenSymbol,
+		PriceQuote: data.PriceQuote,
+		PriceUSD:   data.PriceUsd,
+	}, nil
+}
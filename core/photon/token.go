@@ -1,8 +1,10 @@
 package photon
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"pnl-scan-tool/package/cache"
 	"pnl-scan-tool/package/utils"
 	"strings"
 )
@@ -75,18 +77,25 @@ type TokenInfomation struct {
 //
 // The function returns an error if it fails to fetch the data or parse the JSON
 // response.
-func (p *Token) TokenInfomation() (*TokenInfomation, error) {
+func (p *Token) TokenInfomation(ctx context.Context) (*TokenInfomation, error) {
 
 	apiUrl := fmt.Sprintf("https://photon-sol.tinyastro.io/en/lp/%s", p.TokenAddress)
 
-	result, err := fetchDataPhotonFromAPI(apiUrl)
+	// The page carries the token price
+	result, cached := cache.Get(ctx, cache.Price, apiUrl)
 
-	data := string(result)
+	if !cached {
+		var err error
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch data: %v", err)
+		result, err = fetchDataPhotonFromAPI(ctx, apiUrl)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch data: %w", err)
+		}
 	}
 
+	data := string(result)
+
 	// Extract the relevant JSON data from the JavaScript
 	jsonStart := strings.Index(data, "window.taConfig.show = ")
 
@@ -114,11 +123,15 @@ func (p *Token) TokenInfomation() (*TokenInfomation, error) {
 	decoder := json.NewDecoder(strings.NewReader(jsonStr))
 	decoder.UseNumber()
 
-	err = json.Unmarshal([]byte(jsonStr), &tokenInfo)
+	err := json.Unmarshal([]byte(jsonStr), &tokenInfo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %v\nJSON string: %s", err, jsonStr)
 	}
 
+	if !cached {
+		cache.Set(ctx, cache.Price, apiUrl, result)
+	}
+
 	return &tokenInfo, nil
 }
 
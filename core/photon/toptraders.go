@@ -1,8 +1,11 @@
 package photon
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
 )
 
 // Attributes represents the attributes of a transaction
@@ -31,21 +34,31 @@ type TopTraders struct {
 	Data []TopTrader `json:"data"`
 }
 
-func (t *Token) TopTraders(poolId int) ([]TopTrader, error) {
+func (t *Token) TopTraders(ctx context.Context, poolId int) ([]TopTrader, error) {
 	apiUrl := fmt.Sprintf("https://photon-sol.tinyastro.io/api/events/top_traders?order_by=timestamp&order_dir=dir&pool_id=%d&page=1", poolId)
 
-	data, err := fetchDataPhotonFromAPI(apiUrl)
+	data, cached := cache.Get(ctx, cache.Holders, apiUrl)
 
-	if err != nil {
-		return nil, err
+	if !cached {
+		var err error
+
+		data, err = fetchDataPhotonFromAPI(ctx, apiUrl)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var topTraders TopTraders
 
-	err = json.Unmarshal(data, &topTraders)
+	err := json.Unmarshal(data, &topTraders)
 
 	if err != nil {
-		return nil, err
+		return nil, source.SchemaError(err)
+	}
+
+	if !cached {
+		cache.Set(ctx, cache.Holders, apiUrl, data)
 	}
 
 	return topTraders.Data, nil
@@ -1,8 +1,11 @@
 package photon
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/cache"
 )
 
 type Wallet struct {
@@ -50,20 +53,33 @@ type Transactions struct {
 //
 // The function returns a list of Transaction objects which represent the transactions of the given wallet address.
 // If the function fails, it will return an error.
-func (w *Wallet) Transactions(poolId int) ([]Transaction, error) {
+func (w *Wallet) Transactions(ctx context.Context, poolId int) ([]Transaction, error) {
 	apiUrl := fmt.Sprintf("https://photon-sol.tinyastro.io/api/lp/events?old_pool=false&order_by=timestamp&order_dir=desc&pool_id=%d&signer=%s", poolId, w.WalletAddress)
-	data, err := fetchDataPhotonFromAPI(apiUrl)
 
-	if err != nil {
-		return nil, err
+	// Photon lists every event of the wallet on one page, newest first, so the
+	// page changes as the wallet trades
+	data, cached := cache.Get(ctx, cache.Recent, apiUrl)
+
+	if !cached {
+		var err error
+
+		data, err = fetchDataPhotonFromAPI(ctx, apiUrl)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var transactions Transactions
 
-	err = json.Unmarshal(data, &transactions)
+	err := json.Unmarshal(data, &transactions)
 
 	if err != nil {
-		return nil, err
+		return nil, source.SchemaError(err)
+	}
+
+	if !cached {
+		cache.Set(ctx, cache.Recent, apiUrl, data)
 	}
 
 	return transactions.Data, nil
@@ -0,0 +1,35 @@
+package solanarpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ReplayHandler is a stand-in for a Solana RPC node that answers calls with
+// the results a Client recorded to dir. Serve it with net/http or
+// httptest.NewServer and point a Client at it to run scans offline.
+func ReplayHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request rpcRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := rpcResponse{JSONRPC: "2.0", ID: request.ID}
+
+		result, err := os.ReadFile(filepath.Join(dir, recordingName(request.Method, request.Params)))
+
+		if err != nil {
+			response.Error = &rpcError{Code: -32001, Message: "no recording: " + err.Error()}
+		} else {
+			response.Result = result
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	})
+}
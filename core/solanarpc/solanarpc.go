@@ -0,0 +1,160 @@
+package solanarpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/utils"
+	"strings"
+)
+
+// Client calls a Solana JSON-RPC endpoint.
+type Client struct {
+	Endpoint string
+	// RecordDir, when set, receives a copy of every result so that it can be
+	// served again by ReplayHandler.
+	RecordDir string
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// NewClient creates a client for the given endpoint.
+func NewClient(endpoint string) *Client {
+	return &Client{Endpoint: endpoint}
+}
+
+// call invokes method and decodes its result into result.
+func (c *Client) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	// utils.Client paces the endpoint and retries it while it rate limits
+	resp, err := utils.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch data: %w", err)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("solana rpc %s failed: %w: %s", method, source.StatusError(resp.StatusCode), data)
+	}
+
+	var response rpcResponse
+
+	if err := json.Unmarshal(data, &response); err != nil {
+		return source.SchemaError(err)
+	}
+
+	if response.Error != nil {
+		return response.Error
+	}
+
+	if c.RecordDir != "" {
+		if err := c.record(method, params, response.Result); err != nil {
+			fmt.Println("Error recording Solana RPC response:", err)
+		}
+	}
+
+	return json.Unmarshal(response.Result, result)
+}
+
+func (c *Client) record(method string, params []interface{}, result json.RawMessage) error {
+	if err := os.MkdirAll(c.RecordDir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(c.RecordDir, recordingName(method, params)), result, 0644)
+}
+
+// recordingName names the file holding the result of a call. Calls are told
+// apart by their method, their first parameter and the pagination cursor.
+func recordingName(method string, params []interface{}) string {
+	parts := []string{method}
+
+	for _, param := range params {
+		switch value := param.(type) {
+		case string:
+			parts = append(parts, value)
+		case map[string]interface{}:
+			if before, ok := value["before"].(string); ok && before != "" {
+				parts = append(parts, before)
+			}
+		}
+	}
+
+	return strings.Join(parts, "_") + ".json"
+}
+
+// GetSignaturesForAddress returns up to limit signatures of transactions
+// involving address, newest first, older than before when it is set.
+func (c *Client) GetSignaturesForAddress(ctx context.Context, address string, before string, limit int) ([]SignatureInfo, error) {
+	options := map[string]interface{}{"limit": limit}
+
+	if before != "" {
+		options["before"] = before
+	}
+
+	var signatures []SignatureInfo
+
+	err := c.call(ctx, "getSignaturesForAddress", []interface{}{address, options}, &signatures)
+
+	return signatures, err
+}
+
+// GetTransaction returns a confirmed transaction in jsonParsed encoding. It
+// returns nil when the node does not have it.
+func (c *Client) GetTransaction(ctx context.Context, signature string) (*Transaction, error) {
+	options := map[string]interface{}{
+		"encoding":                       "jsonParsed",
+		"maxSupportedTransactionVersion": 0,
+		"commitment":                     "confirmed",
+	}
+
+	var transaction *Transaction
+
+	err := c.call(ctx, "getTransaction", []interface{}{signature, options}, &transaction)
+
+	return transaction, err
+}
@@ -0,0 +1,221 @@
+package solanarpc
+
+import (
+	"context"
+	"fmt"
+	"pnl-scan-tool/core/source"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"sync"
+	"time"
+)
+
+const (
+	signaturePageSize = 1000
+	// DefaultMaxSignatures bounds how far back a wallet is read.
+	DefaultMaxSignatures = 5000
+	// maxCachedWallets bounds the decoded wallets kept between calls.
+	maxCachedWallets = 16
+	// walletCacheTTL is how long a decoded wallet is reused by Trades.
+	walletCacheTTL = 10 * time.Minute
+)
+
+// Source serves Solana trades decoded from a JSON-RPC node. It has no price
+// feed, so it is meant to be paired with one in a source.Fallback.
+type Source struct {
+	Client        *Client
+	MaxSignatures int
+
+	mu      sync.Mutex
+	wallets map[string]walletEvents
+}
+
+// walletEvents are the decoded transactions of a wallet. Truncated is set
+// when MaxSignatures was reached before the start of the scanned period.
+// Newest is the signature of the latest transaction read.
+type walletEvents struct {
+	events    []TokenEvent
+	truncated bool
+	newest    string
+	readAt    time.Time
+}
+
+// NewSource creates a source reading from the given RPC endpoint.
+func NewSource(endpoint string) *Source {
+	return &Source{
+		Client:        NewClient(endpoint),
+		MaxSignatures: DefaultMaxSignatures,
+		wallets:       make(map[string]walletEvents),
+	}
+}
+
+func (s *Source) Name() string {
+	return "solanarpc"
+}
+
+// TradedTokens decodes the transactions of the wallet of the last days days,
+// or all of them when days is 0, and lists the tokens they moved, most recent
+// first.
+func (s *Source) TradedTokens(ctx context.Context, chain string, wallet string, days int) ([]source.Token, error) {
+	if chain != "sol" {
+		return nil, source.ErrNotSupported
+	}
+
+	read, err := s.read(ctx, wallet, days)
+
+	if err != nil {
+		return nil, err
+	}
+
+	events := read.events
+	seen := make(map[string]bool)
+	var tokens []source.Token
+
+	for i := len(events) - 1; i >= 0; i-- {
+		mint := events[i].Mint
+
+		if !seen[mint] {
+			seen[mint] = true
+			tokens = append(tokens, source.Token{Address: mint})
+		}
+	}
+
+	return tokens, nil
+}
+
+// Trades returns the decoded trades of one token over the whole history of
+// the wallet, whatever window TradedTokens listed it from, so that the buys
+// made before the window still count in the cost basis.
+func (s *Source) Trades(ctx context.Context, chain string, wallet string, token string) (pnlmodel.TradeHistory, error) {
+	if chain != "sol" {
+		return pnlmodel.TradeHistory{}, source.ErrNotSupported
+	}
+
+	s.mu.Lock()
+	read, ok := s.wallets[cacheKey(wallet, 0)]
+	s.mu.Unlock()
+
+	if !ok || time.Since(read.readAt) > walletCacheTTL {
+		var err error
+
+		if read, err = s.read(ctx, wallet, 0); err != nil {
+			return pnlmodel.TradeHistory{}, err
+		}
+	}
+
+	history := pnlmodel.TradeHistory{TokenAddress: token, Truncated: read.truncated}
+
+	for _, event := range read.events {
+		if event.Mint == token {
+			history.EventTrades = append(history.EventTrades, event.EventTrade)
+		}
+	}
+
+	return history, nil
+}
+
+func (s *Source) TopTraders(ctx context.Context, chain string, token string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (s *Source) TopHolders(ctx context.Context, chain string, token string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (s *Source) Price(ctx context.Context, chain string, token string) (source.Price, error) {
+	return source.Price{}, source.ErrNotSupported
+}
+
+// read decodes the transactions of a wallet of the last days days, oldest
+// first, and caches them for the calls that follow. A windowed read that
+// finds transactions newer than the cached whole history drops it, so that
+// Trades reads it again.
+func (s *Source) read(ctx context.Context, wallet string, days int) (walletEvents, error) {
+	var events []TokenEvent
+	var since int64
+
+	if days != 0 {
+		since = time.Now().AddDate(0, 0, -days).Unix()
+	}
+
+	signatures, truncated, err := s.signatures(ctx, wallet, since)
+
+	if err != nil {
+		return walletEvents{}, err
+	}
+
+	// Signatures come newest first
+	for i := len(signatures) - 1; i >= 0; i-- {
+		if signatures[i].Err != nil {
+			continue
+		}
+
+		transaction, err := s.Client.GetTransaction(ctx, signatures[i].Signature)
+
+		if err != nil {
+			return walletEvents{}, err
+		}
+
+		events = append(events, DecodeTransaction(transaction, wallet)...)
+	}
+
+	read := walletEvents{events: events, truncated: truncated, readAt: time.Now()}
+
+	if len(signatures) != 0 {
+		read.newest = signatures[0].Signature
+	}
+
+	s.mu.Lock()
+	if all, ok := s.wallets[cacheKey(wallet, 0)]; ok && days != 0 && read.newest != "" && all.newest != read.newest {
+		delete(s.wallets, cacheKey(wallet, 0))
+	}
+	if len(s.wallets) >= maxCachedWallets {
+		for cached := range s.wallets {
+			delete(s.wallets, cached)
+			break
+		}
+	}
+	s.wallets[cacheKey(wallet, days)] = read
+	s.mu.Unlock()
+
+	return read, nil
+}
+
+// cacheKey keys the decoded transactions of a wallet on the window they were
+// read over.
+func cacheKey(wallet string, days int) string {
+	return fmt.Sprintf("%s/%d", wallet, days)
+}
+
+// signatures pages through the signatures of a wallet back to since, or as
+// far as MaxSignatures allows. It reports whether MaxSignatures cut the
+// history short.
+func (s *Source) signatures(ctx context.Context, wallet string, since int64) ([]SignatureInfo, bool, error) {
+	var signatures []SignatureInfo
+	before := ""
+
+	for {
+		page, err := s.Client.GetSignaturesForAddress(ctx, wallet, before, signaturePageSize)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, signature := range page {
+			if since != 0 && signature.BlockTime != nil && *signature.BlockTime < since {
+				return signatures, false, nil
+			}
+
+			if s.MaxSignatures != 0 && len(signatures) >= s.MaxSignatures {
+				return signatures, true, nil
+			}
+
+			signatures = append(signatures, signature)
+		}
+
+		if len(page) < signaturePageSize {
+			return signatures, false, nil
+		}
+
+		before = page[len(page)-1].Signature
+	}
+}
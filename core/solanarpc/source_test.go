@@ -0,0 +1,191 @@
+package solanarpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pnl-scan-tool/core/source/sourcetest"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+const (
+	testWallet = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+	testSender = "3dsU5vMSc7PZ1P5ZNGt8HKazM6K16SCgGVyDLhbdQ9fm"
+	tokenA     = "7GCihgDB8fe6KNjn2MYtkzZcRjQy3t9GHdC8uHYmW2hr"
+	tokenB     = "EKpQGSJtjMFqKZ9KQanSqYXRcF8fBopzLHYxdM65zcjm"
+	tokenC     = "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+	replayDir  = "testdata/replay"
+)
+
+func newReplaySource(t *testing.T) *Source {
+	t.Helper()
+
+	server := httptest.NewServer(ReplayHandler(replayDir))
+	t.Cleanup(server.Close)
+
+	return NewSource(server.URL)
+}
+
+func loadTransaction(t *testing.T, signature string) *Transaction {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(replayDir, "getTransaction_"+signature+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var transaction Transaction
+	if err := json.Unmarshal(data, &transaction); err != nil {
+		t.Fatal(err)
+	}
+
+	return &transaction
+}
+
+func TestDecodeTransactionBuyWithJitoTip(t *testing.T) {
+	events := DecodeTransaction(loadTransaction(t, "buySignature"), testWallet)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	if events[0].Mint != tokenA || events[0].Program != "raydium-amm" {
+		t.Errorf("event = %s on %s, want %s on raydium-amm", events[0].Mint, events[0].Program, tokenA)
+	}
+
+	// The tip is a fee, not part of the price paid
+	sourcetest.AssertTrade(t, events[0].EventTrade, pnlmodel.EventTrade{
+		TxHash:       "buySignature",
+		EventType:    pnlmodel.EventBuy,
+		TokensAmount: 1000,
+		QuoteAmount:  1,
+		Price:        0.001,
+		Fee:          0.000105,
+		Timestamp:    1700000000,
+	})
+}
+
+func TestDecodeTransactionSkipsTokenToTokenSwap(t *testing.T) {
+	if events := DecodeTransaction(loadTransaction(t, "swapSignature"), testWallet); len(events) != 0 {
+		t.Errorf("got %d events for a token to token swap, want none", len(events))
+	}
+}
+
+func TestDecodeTransactionTransferIn(t *testing.T) {
+	events := DecodeTransaction(loadTransaction(t, "transferSignature"), testWallet)
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	// The sender paid the fee
+	sourcetest.AssertTrade(t, events[0].EventTrade, pnlmodel.EventTrade{
+		TxHash:       "transferSignature",
+		EventType:    pnlmodel.EventTransferIn,
+		TokensAmount: 20,
+		Counterparty: testSender,
+		Timestamp:    1700000200,
+	})
+}
+
+func TestSourceReplay(t *testing.T) {
+	s := newReplaySource(t)
+	ctx := context.Background()
+
+	tokens, err := s.TradedTokens(ctx, "sol", testWallet, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 2 || tokens[0].Address != tokenB || tokens[1].Address != tokenA {
+		t.Fatalf("tokens = %+v, want %s then %s", tokens, tokenB, tokenA)
+	}
+
+	history, err := s.Trades(ctx, "sol", testWallet, tokenA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.EventTrades) != 2 {
+		t.Fatalf("got %d trades of %s, want 2", len(history.EventTrades), tokenA)
+	}
+
+	sourcetest.AssertTrade(t, history.EventTrades[0], pnlmodel.EventTrade{
+		TxHash:       "buySignature",
+		EventType:    pnlmodel.EventBuy,
+		TokensAmount: 1000,
+		QuoteAmount:  1,
+		Price:        0.001,
+		Fee:          0.000105,
+		Timestamp:    1700000000,
+	})
+
+	sourcetest.AssertTrade(t, history.EventTrades[1], pnlmodel.EventTrade{
+		TxHash:       "sellSignature",
+		EventType:    pnlmodel.EventSell,
+		TokensAmount: 600,
+		QuoteAmount:  0.5,
+		Price:        0.5 / 600,
+		Fee:          0.000005,
+		Timestamp:    1700000100,
+	})
+
+	history, err = s.Trades(ctx, "sol", testWallet, tokenC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.EventTrades) != 0 {
+		t.Errorf("got %d trades of %s, want none", len(history.EventTrades), tokenC)
+	}
+}
+
+func TestSourceTradesIgnoresWindow(t *testing.T) {
+	s := newReplaySource(t)
+	ctx := context.Background()
+
+	// Every recorded trade is older than a day
+	tokens, err := s.TradedTokens(ctx, "sol", testWallet, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 0 {
+		t.Fatalf("got %d tokens traded in the last day, want none", len(tokens))
+	}
+
+	history, err := s.Trades(ctx, "sol", testWallet, tokenA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.EventTrades) != 2 {
+		t.Errorf("got %d trades of %s after a windowed read, want the 2 of its whole history", len(history.EventTrades), tokenA)
+	}
+}
+
+func TestSourceTradesRereadsAfterNewerSignatures(t *testing.T) {
+	s := newReplaySource(t)
+	ctx := context.Background()
+
+	// A whole history read before the wallet last traded
+	s.wallets[cacheKey(testWallet, 0)] = walletEvents{newest: "olderSignature", readAt: time.Now()}
+
+	if _, err := s.TradedTokens(ctx, "sol", testWallet, 100000); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := s.Trades(ctx, "sol", testWallet, tokenA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.EventTrades) != 2 {
+		t.Errorf("got %d trades of %s, want the 2 read after the newer signatures", len(history.EventTrades), tokenA)
+	}
+}
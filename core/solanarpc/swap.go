@@ -0,0 +1,321 @@
+package solanarpc
+
+import (
+	"encoding/json"
+	"math"
+	"pnl-scan-tool/package/utils"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"strconv"
+)
+
+const (
+	lamportsPerSOL = 1e9
+	wrappedSOL     = "So11111111111111111111111111111111111111112"
+
+	// dustSOL is the smallest SOL change that counts as a trade leg.
+	dustSOL = 0.000001
+	// dustToken is the smallest token change that counts at all.
+	dustToken = 1e-12
+)
+
+// dexPrograms are the swap programs whose transactions are decoded as
+// trades. Jupiter routes through the others with CPIs.
+var dexPrograms = map[string]string{
+	"675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8": "raydium-amm",
+	"CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C": "raydium-cpmm",
+	"6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P":  "pump.fun",
+	"whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc":  "orca-whirlpool",
+	"JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4":  "jupiter",
+}
+
+// jitoTipAccounts receive Jito bundle tips, which are counted as fees.
+var jitoTipAccounts = map[string]bool{
+	"96gYZGLnJYVFmbjzopPSU6QiEV5fGqZNyN9nmNhvrZU5": true,
+	"HFqU5x63VTqvQss8hp11i4wVV8bD44PvwucfZ2bU7gRe": true,
+	"Cw8CFyM9FkoMi7K7Crf6HNQqf4uEMzpKw6QNghXLvLkY": true,
+	"ADaUMid9yfUytqMBgopwjb2DTLSokTSzL1zt6iGPaS49": true,
+	"DfXygSm4jCyNCybVYYK6DwvWqjKee8pbDmJGcLWNDXjh": true,
+	"ADuUkR4vqLUMWXxW9gh6D6L8pMSawimctcNZ5pGwDcEt": true,
+	"DttWaMuVvTiduZRnguLF7jNxTgiMBZ1hyAumKUiL2KRL": true,
+	"3AVi9Tg9Uo68tJfuvoKvqKNWKc5wPdSSdeBnizKZ6jT":  true,
+}
+
+// TokenEvent is the change of one token in a wallet made by a transaction.
+type TokenEvent struct {
+	Mint       string
+	Program    string
+	EventTrade pnlmodel.EventTrade
+}
+
+// DecodeTransaction reconstructs what a transaction did to the tokens of
+// wallet.
+//
+// Trades are read from balance changes rather than from each AMM's
+// instruction layout: the SOL leg is the change of the lamports the wallet
+// controls, its own account plus its token accounts, so that wrapped SOL and
+// token account rent cancel out. A transaction that runs one of the known
+// swap programs and moves exactly one token against SOL is a buy or a sell.
+// Other token changes are transfers, mints or burns. Failed transactions and
+// swaps between two tokens are skipped.
+func DecodeTransaction(transaction *Transaction, wallet string) []TokenEvent {
+	if transaction == nil || transaction.Meta == nil || transaction.Meta.Err != nil {
+		return nil
+	}
+
+	meta := transaction.Meta
+	keys := transaction.Transaction.Message.AccountKeys
+
+	owned := map[int]bool{}
+
+	for i, key := range keys {
+		if key.Pubkey == wallet {
+			owned[i] = true
+		}
+	}
+
+	tokenDeltas := map[string]float64{}
+	var mints []string
+
+	addBalances := func(balances []TokenBalance, sign float64) {
+		for _, balance := range balances {
+			if balance.Owner != wallet {
+				continue
+			}
+
+			owned[balance.AccountIndex] = true
+
+			if balance.Mint == wrappedSOL {
+				continue
+			}
+
+			if _, seen := tokenDeltas[balance.Mint]; !seen {
+				mints = append(mints, balance.Mint)
+			}
+
+			tokenDeltas[balance.Mint] += sign * uiAmount(balance.UiTokenAmount)
+		}
+	}
+
+	addBalances(meta.PreTokenBalances, -1)
+	addBalances(meta.PostTokenBalances, 1)
+
+	var lamports int64
+
+	for i := range owned {
+		if i < len(meta.PreBalances) && i < len(meta.PostBalances) {
+			lamports += int64(meta.PostBalances[i]) - int64(meta.PreBalances[i])
+		}
+	}
+
+	instructions := allInstructions(transaction)
+
+	var feeLamports uint64
+
+	if len(keys) > 0 && keys[0].Pubkey == wallet {
+		feeLamports = meta.Fee
+	}
+
+	for _, instruction := range instructions {
+		parsed, ok := parseInstruction(instruction)
+
+		if !ok || instruction.Program != "system" || parsed.Type != "transfer" {
+			continue
+		}
+
+		if stringInfo(parsed.Info, "source") == wallet && jitoTipAccounts[stringInfo(parsed.Info, "destination")] {
+			feeLamports += uint64(utils.ConvertToFloat64(parsed.Info["lamports"]))
+		}
+	}
+
+	// The SOL the wallet gave or got for the tokens, without fees and tips
+	solDelta := float64(lamports+int64(feeLamports)) / lamportsPerSOL
+	fee := float64(feeLamports) / lamportsPerSOL
+
+	program := swapProgram(instructions)
+
+	var events []TokenEvent
+
+	var nonZero []string
+	for _, mint := range mints {
+		if math.Abs(tokenDeltas[mint]) > dustToken {
+			nonZero = append(nonZero, mint)
+		}
+	}
+
+	timestamp := int64(0)
+	if transaction.BlockTime != nil {
+		timestamp = *transaction.BlockTime
+	}
+
+	txHash := ""
+	if len(transaction.Transaction.Signatures) > 0 {
+		txHash = transaction.Transaction.Signatures[0]
+	}
+
+	newEvent := func(mint string, eventType string, tokens float64, quote float64) TokenEvent {
+		eventTrade := pnlmodel.EventTrade{
+			TxHash:       txHash,
+			EventType:    eventType,
+			QuoteAmount:  quote,
+			TokensAmount: tokens,
+			Timestamp:    timestamp,
+			DateTime:     utils.ConvertTimestampToDate(timestamp),
+		}
+
+		if tokens != 0 {
+			eventTrade.Price = quote / tokens
+		}
+
+		return TokenEvent{Mint: mint, Program: program, EventTrade: eventTrade}
+	}
+
+	switch {
+	case program != "" && len(nonZero) == 1 && math.Abs(solDelta) > dustSOL && (tokenDeltas[nonZero[0]] > 0) != (solDelta > 0):
+		mint := nonZero[0]
+
+		if tokenDeltas[mint] > 0 {
+			events = append(events, newEvent(mint, pnlmodel.EventBuy, tokenDeltas[mint], -solDelta))
+		} else {
+			events = append(events, newEvent(mint, pnlmodel.EventSell, -tokenDeltas[mint], solDelta))
+		}
+	case program != "" && len(nonZero) > 1:
+		// A swap between two tokens has no SOL leg to price it in
+	default:
+		for _, mint := range nonZero {
+			delta := tokenDeltas[mint]
+
+			event := newEvent(mint, transferType(instructions, mint, delta), math.Abs(delta), 0)
+			event.EventTrade.Counterparty = counterparty(meta, wallet, mint, delta)
+
+			events = append(events, event)
+		}
+	}
+
+	if len(events) > 0 {
+		events[0].EventTrade.Fee = fee
+	}
+
+	return events
+}
+
+// allInstructions returns the outer and inner instructions of a transaction.
+func allInstructions(transaction *Transaction) []Instruction {
+	instructions := append([]Instruction{}, transaction.Transaction.Message.Instructions...)
+
+	for _, inner := range transaction.Meta.InnerInstructions {
+		instructions = append(instructions, inner.Instructions...)
+	}
+
+	return instructions
+}
+
+// swapProgram returns the name of the swap program run by a transaction. A
+// Jupiter route is reported as Jupiter rather than as the AMMs it went through.
+func swapProgram(instructions []Instruction) string {
+	program := ""
+
+	for _, instruction := range instructions {
+		name, ok := dexPrograms[instruction.ProgramId]
+
+		if !ok {
+			continue
+		}
+
+		if name == "jupiter" {
+			return name
+		}
+
+		if program == "" {
+			program = name
+		}
+	}
+
+	return program
+}
+
+// transferType classifies a token change that is not a swap.
+func transferType(instructions []Instruction, mint string, delta float64) string {
+	for _, instruction := range instructions {
+		parsed, ok := parseInstruction(instruction)
+
+		if !ok || stringInfo(parsed.Info, "mint") != mint {
+			continue
+		}
+
+		switch parsed.Type {
+		case "mintTo", "mintToChecked":
+			if delta > 0 {
+				return pnlmodel.EventMint
+			}
+		case "burn", "burnChecked":
+			if delta < 0 {
+				return pnlmodel.EventBurn
+			}
+		}
+	}
+
+	if delta > 0 {
+		return pnlmodel.EventTransferIn
+	}
+
+	return pnlmodel.EventTransferOut
+}
+
+// counterparty finds the owner whose balance of mint moved the most the
+// other way.
+func counterparty(meta *TransactionMeta, wallet string, mint string, delta float64) string {
+	deltas := map[string]float64{}
+
+	for _, balance := range meta.PreTokenBalances {
+		if balance.Mint == mint && balance.Owner != wallet {
+			deltas[balance.Owner] -= uiAmount(balance.UiTokenAmount)
+		}
+	}
+
+	for _, balance := range meta.PostTokenBalances {
+		if balance.Mint == mint && balance.Owner != wallet {
+			deltas[balance.Owner] += uiAmount(balance.UiTokenAmount)
+		}
+	}
+
+	found := ""
+	largest := dustToken
+
+	for owner, other := range deltas {
+		if (other > 0) != (delta > 0) && math.Abs(other) > largest {
+			found = owner
+			largest = math.Abs(other)
+		}
+	}
+
+	return found
+}
+
+func parseInstruction(instruction Instruction) (ParsedInstruction, bool) {
+	var parsed ParsedInstruction
+
+	if len(instruction.Parsed) == 0 || instruction.Parsed[0] != '{' {
+		return parsed, false
+	}
+
+	if err := json.Unmarshal(instruction.Parsed, &parsed); err != nil {
+		return parsed, false
+	}
+
+	return parsed, true
+}
+
+func stringInfo(info map[string]interface{}, key string) string {
+	value, _ := info[key].(string)
+	return value
+}
+
+func uiAmount(amount UiTokenAmount) float64 {
+	value, err := strconv.ParseFloat(amount.UiAmountString, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return value
+}
@@ -0,0 +1,80 @@
+package solanarpc
+
+import "encoding/json"
+
+type SignatureInfo struct {
+	Signature string      `json:"signature"`
+	Slot      uint64      `json:"slot"`
+	BlockTime *int64      `json:"blockTime"`
+	Err       interface{} `json:"err"`
+}
+
+type Transaction struct {
+	Slot        uint64             `json:"slot"`
+	BlockTime   *int64             `json:"blockTime"`
+	Meta        *TransactionMeta   `json:"meta"`
+	Transaction TransactionPayload `json:"transaction"`
+}
+
+type TransactionPayload struct {
+	Signatures []string `json:"signatures"`
+	Message    Message  `json:"message"`
+}
+
+type Message struct {
+	AccountKeys  []AccountKey  `json:"accountKeys"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// AccountKey lists every account of the transaction, including the ones
+// loaded from address lookup tables.
+type AccountKey struct {
+	Pubkey   string `json:"pubkey"`
+	Signer   bool   `json:"signer"`
+	Writable bool   `json:"writable"`
+	Source   string `json:"source"`
+}
+
+type TransactionMeta struct {
+	Err               interface{}        `json:"err"`
+	Fee               uint64             `json:"fee"`
+	PreBalances       []uint64           `json:"preBalances"`
+	PostBalances      []uint64           `json:"postBalances"`
+	PreTokenBalances  []TokenBalance     `json:"preTokenBalances"`
+	PostTokenBalances []TokenBalance     `json:"postTokenBalances"`
+	InnerInstructions []InnerInstruction `json:"innerInstructions"`
+	LogMessages       []string           `json:"logMessages"`
+}
+
+type TokenBalance struct {
+	AccountIndex  int           `json:"accountIndex"`
+	Mint          string        `json:"mint"`
+	Owner         string        `json:"owner"`
+	UiTokenAmount UiTokenAmount `json:"uiTokenAmount"`
+}
+
+type UiTokenAmount struct {
+	Amount         string `json:"amount"`
+	Decimals       int    `json:"decimals"`
+	UiAmountString string `json:"uiAmountString"`
+}
+
+type InnerInstruction struct {
+	Index        int           `json:"index"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// Instruction is either parsed by the node, for the programs it knows, or
+// left as raw accounts and data.
+type Instruction struct {
+	ProgramId string          `json:"programId"`
+	Program   string          `json:"program"`
+	Parsed    json.RawMessage `json:"parsed"`
+	Accounts  []string        `json:"accounts"`
+	Data      string          `json:"data"`
+}
+
+type ParsedInstruction struct {
+	Type string                 `json:"type"`
+	Info map[string]interface{} `json:"info"`
+}
@@ -1,7 +1,7 @@
 package solscan
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -47,7 +47,7 @@ type Solscan struct {
 //
 // The function returns a list of Transfer objects which represent the transactions of the given wallet address.
 // If the function fails, it will return an error.
-func (s *Solscan) GetTransactions(scanDay int) ([]Transfer, error) {
+func (s *Solscan) GetTransactions(ctx context.Context, scanDay int) ([]Transfer, error) {
 	url := fmt.Sprintf("https://api-v2.solscan.io/v2/account/transfer/export?address=%s&exclude_token=%s&flow=%s", s.Address, s.ExcludeToken, s.Flow)
 
 	var collection string
@@ -61,7 +61,7 @@ func (s *Solscan) GetTransactions(scanDay int) ([]Transfer, error) {
 
 	filter := bson.M{"walletaddress": s.Address}
 
-	_, err = mongodb.FindOne(collection, filter)
+	_, err = mongodb.FindOne(ctx, collection, filter)
 
 	if err == nil && scanDay != 0 {
 		fmt.Println("Wallet Scan PNL already exists in the database.")
@@ -70,53 +70,31 @@ func (s *Solscan) GetTransactions(scanDay int) ([]Transfer, error) {
 
 	fmt.Println("Requesting URL:", url)
 
-	// Create a new HTTP client with a 30-second timeout
-	client := &http.Client{
-		Timeout: time.Second * 30,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: false, // Enable certificate verification
-			},
-		},
+	// Create a new request
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
-	// Implement retry with exponential backoff
-	maxRetries := 3
-	var resp *http.Response
-
-	// Retry loop
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Create a new request
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %v", err)
-		}
-
-		// Set headers to mimic a real browser request
-		req.Header.Set("accept", "application/json")
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36")
-		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-		req.Header.Set("Referer", "https://solscan.io/") // Set referer to appear like a browser visit
-		req.Header.Set("Connection", "keep-alive")
-
-		// Set cookies
-		cookie := &http.Cookie{
-			Name:  "cf_clearance",
-			Value: "bEWP4.WnPCpagmL28BvDpU40873WRenpW5JC7loNEb4-1727067484-1.2.1.1-4WfXUd0zr.qdiYt.VHzwlgJcfHw5YV4G5Gv.ushUj649D8slxqNmMFW9_DWNlsZiLQNZpZ5iYqZS_hTV5PVZz9AG.Rkj8mou7vPKGCvuIsZROoxKTzszvw0NMOgFnCyZ86BVmCOu9YDxpkZaii5QXEsZYTO_FzCuoT9keR5yW4O7So7kSZde5akNgGL5vZfOkGEK5q9pH38zo234CCTIyqOYwveCmTyOMFTTeEHZT4A0fiDKK2bV3EL329tYWlkill1eEY5YfokBrtZ_C7IIeEkkW5SWTpa_PxASAHemcaRwd9zxbTvq32Kz5u947I27YCb_5_xFB2A7IU3plIXewIqAhR8trXDhxN0of28svVQY7xRLj3RUVG1oW7amUtrqGI2dOF0jvIzTksQKDISIh2gmVb9nAMEcMiQG4PNQPWIhTyyXLXpaHWATfHgcQBmG",
-			Path:  "/",
-		}
-		req.AddCookie(cookie)
-
-		// Make the request
-		resp, err = client.Do(req)
-		if err == nil {
-			// Break the loop if the request was successful
-			break
-		}
+	// Set headers to mimic a real browser request
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Referer", "https://solscan.io/") // Set referer to appear like a browser visit
+	req.Header.Set("Connection", "keep-alive")
+
+	// Set cookies
+	cookie := &http.Cookie{
+		Name:  "cf_clearance",
+		Value: "bEWP4.WnPCpagmL28BvDpU40873WRenpW5JC7loNEb4-1727067484-1.2.1.1-4WfXUd0zr.qdiYt.VHzwlgJcfHw5YV4G5Gv.ushUj649D8slxqNmMFW9_DWNlsZiLQNZpZ5iYqZS_hTV5PVZz9AG.Rkj8mou7vPKGCvuIsZROoxKTzszvw0NMOgFnCyZ86BVmCOu9YDxpkZaii5QXEsZYTO_FzCuoT9keR5yW4O7So7kSZde5akNgGL5vZfOkGEK5q9pH38zo234CCTIyqOYwveCmTyOMFTTeEHZT4A0fiDKK2bV3EL329tYWlkill1eEY5YfokBrtZ_C7IIeEkkW5SWTpa_PxASAHemcaRwd9zxbTvq32Kz5u947I27YCb_5_xFB2A7IU3plIXewIqAhR8trXDhxN0of28svVQY7xRLj3RUVG1oW7amUtrqGI2dOF0jvIzTksQKDISIh2gmVb9nAMEcMiQG4PNQPWIhTyyXLXpaHWATfHgcQBmG",
+		Path:  "/",
+	}
+	req.AddCookie(cookie)
 
-		// Print the error and retry after exponential backoff
-		fmt.Printf("Attempt %d failed: %v\n", attempt+1, err)
-		time.Sleep(time.Second * time.Duration(1<<uint(attempt))) // Exponential backoff
+	// Make the request, utils.Client retries network errors with backoff
+	resp, err := utils.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
 	}
 
 	defer resp.Body.Close()
@@ -0,0 +1,56 @@
+package solscan
+
+import (
+	"context"
+	"pnl-scan-tool/core/source"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+// Source lists the tokens of a Solana wallet from its Solscan transfers.
+type Source struct{}
+
+func (Source) Name() string {
+	return "solscan"
+}
+
+func (Source) TradedTokens(ctx context.Context, chain string, wallet string, days int) ([]source.Token, error) {
+	if chain != "sol" {
+		return nil, source.ErrNotSupported
+	}
+
+	s := Solscan{
+		Address:      wallet,
+		ExcludeToken: "So11111111111111111111111111111111111111111",
+		Flow:         "in",
+	}
+
+	transfers, err := s.GetTransactions(ctx, days)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]source.Token, 0, len(transfers))
+	for _, transfer := range transfers {
+		tokens = append(tokens, source.Token{Address: transfer.TokenAddress})
+	}
+
+	return tokens, nil
+}
+
+// Trades is not served by Solscan, whose export carries no prices.
+func (Source) Trades(ctx context.Context, chain string, wallet string, token string) (pnlmodel.TradeHistory, error) {
+	return pnlmodel.TradeHistory{}, source.ErrNotSupported
+}
+
+func (Source) TopTraders(ctx context.Context, chain string, token string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (Source) TopHolders(ctx context.Context, chain string, token string) ([]string, error) {
+	return nil, source.ErrNotSupported
+}
+
+func (Source) Price(ctx context.Context, chain string, token string) (source.Price, error) {
+	return source.Price{}, source.ErrNotSupported
+}
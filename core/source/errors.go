@@ -0,0 +1,68 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"pnl-scan-tool/package/transport"
+)
+
+// Kinds of provider failures. Provider clients wrap them so that callers can
+// tell with errors.Is whether to skip a wallet, retry later or abort.
+var (
+	// ErrRateLimited is returned once a provider keeps throttling requests
+	// after its retries.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrAuthExpired is returned when a provider rejects the session cookies
+	// or API key. Nothing more can be fetched until they are renewed.
+	ErrAuthExpired = errors.New("auth or cookies expired")
+	// ErrNotFound is returned for a wallet, token or pair the provider does
+	// not know.
+	ErrNotFound = errors.New("not found")
+	// ErrSchemaChanged is returned when a response no longer decodes into the
+	// provider's model.
+	ErrSchemaChanged = errors.New("upstream schema changed")
+)
+
+// StatusError returns the error kind of a failed HTTP response.
+func StatusError(statusCode int) error {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w: status %d", ErrRateLimited, statusCode)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: status %d", ErrAuthExpired, statusCode)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: status %d", ErrNotFound, statusCode)
+	}
+
+	return fmt.Errorf("unexpected status %d", statusCode)
+}
+
+// SchemaError wraps the error of a response that failed to decode.
+func SchemaError(err error) error {
+	return fmt.Errorf("%w: %v", ErrSchemaChanged, err)
+}
+
+// Kind names the kind of a provider error for reports: "cancelled",
+// "auth_expired", "rate_limited", "schema_changed", "not_found",
+// "not_supported" or "other". An error joining several kinds is named after
+// the most severe. A host whose circuit is open counts as rate limiting.
+func Kind(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	case errors.Is(err, ErrAuthExpired):
+		return "auth_expired"
+	case errors.Is(err, ErrRateLimited), errors.Is(err, transport.ErrCircuitOpen):
+		return "rate_limited"
+	case errors.Is(err, ErrSchemaChanged):
+		return "schema_changed"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrNotSupported):
+		return "not_supported"
+	}
+
+	return "other"
+}
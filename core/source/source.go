@@ -0,0 +1,181 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"strings"
+)
+
+// ErrNotSupported is returned by a source that cannot serve a request, for
+// example a chain or a kind of data it does not cover.
+var ErrNotSupported = errors.New("not supported by source")
+
+// Token is a token a wallet has traded.
+type Token struct {
+	Address string
+	Symbol  string
+}
+
+// Price is the current price of a token.
+type Price struct {
+	Symbol     string
+	PriceQuote float64
+	PriceUSD   float64
+}
+
+// TradeSource provides the trade data a PnL scan needs. Every call stops when
+// its context is done.
+type TradeSource interface {
+	// Name identifies the source in logs and configuration.
+	Name() string
+	// TradedTokens lists the tokens a wallet has traded, most recent first.
+	// A non zero days limits the list to the tokens traded in the last days
+	// days.
+	TradedTokens(ctx context.Context, chain string, wallet string, days int) ([]Token, error)
+	// Trades returns the trades of a wallet in one token, oldest first, with
+	// the mark prices set.
+	Trades(ctx context.Context, chain string, wallet string, token string) (pnlmodel.TradeHistory, error)
+	// TopTraders lists the wallets that traded a token the most profitably.
+	TopTraders(ctx context.Context, chain string, token string) ([]string, error)
+	// TopHolders lists the wallets holding the most of a token.
+	TopHolders(ctx context.Context, chain string, token string) ([]string, error)
+	// Price returns the current price of a token.
+	Price(ctx context.Context, chain string, token string) (Price, error)
+}
+
+// IncrementalSource is a TradeSource that can skip the trades a rescan
+// already has.
+type IncrementalSource interface {
+	TradeSource
+	// TradesSince returns the trades of a wallet in one token made at or
+	// after since, a Unix timestamp, oldest first. The history may repeat
+	// trades made at since.
+	TradesSince(ctx context.Context, chain string, wallet string, token string, since int64) (pnlmodel.TradeHistory, error)
+}
+
+// Fallback tries each source in order and returns the first successful
+// answer, so that one failing provider does not stop a scan. It gives up as
+// soon as the context is done.
+type Fallback []TradeSource
+
+func (f Fallback) Name() string {
+	names := make([]string, len(f))
+	for i, s := range f {
+		names[i] = s.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (f Fallback) TradedTokens(ctx context.Context, chain string, wallet string, days int) ([]Token, error) {
+	var errs []error
+	for _, s := range f {
+		tokens, err := s.TradedTokens(ctx, chain, wallet, days)
+		if err == nil {
+			return tokens, nil
+		}
+		errs = append(errs, sourceError(s, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fallbackError(errs)
+}
+
+func (f Fallback) Trades(ctx context.Context, chain string, wallet string, token string) (pnlmodel.TradeHistory, error) {
+	var errs []error
+	for _, s := range f {
+		history, err := s.Trades(ctx, chain, wallet, token)
+		if err == nil {
+			return history, nil
+		}
+		errs = append(errs, sourceError(s, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return pnlmodel.TradeHistory{}, fallbackError(errs)
+}
+
+// TradesSince asks each source for the trades made since, falling back to
+// the whole history for sources that cannot skip older trades.
+func (f Fallback) TradesSince(ctx context.Context, chain string, wallet string, token string, since int64) (pnlmodel.TradeHistory, error) {
+	var errs []error
+	for _, s := range f {
+		var history pnlmodel.TradeHistory
+		var err error
+		if incremental, ok := s.(IncrementalSource); ok {
+			history, err = incremental.TradesSince(ctx, chain, wallet, token, since)
+		} else {
+			history, err = s.Trades(ctx, chain, wallet, token)
+		}
+		if err == nil {
+			return history, nil
+		}
+		errs = append(errs, sourceError(s, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return pnlmodel.TradeHistory{}, fallbackError(errs)
+}
+
+func (f Fallback) TopTraders(ctx context.Context, chain string, token string) ([]string, error) {
+	var errs []error
+	for _, s := range f {
+		wallets, err := s.TopTraders(ctx, chain, token)
+		if err == nil {
+			return wallets, nil
+		}
+		errs = append(errs, sourceError(s, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fallbackError(errs)
+}
+
+func (f Fallback) TopHolders(ctx context.Context, chain string, token string) ([]string, error) {
+	var errs []error
+	for _, s := range f {
+		wallets, err := s.TopHolders(ctx, chain, token)
+		if err == nil {
+			return wallets, nil
+		}
+		errs = append(errs, sourceError(s, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fallbackError(errs)
+}
+
+func (f Fallback) Price(ctx context.Context, chain string, token string) (Price, error) {
+	var errs []error
+	for _, s := range f {
+		price, err := s.Price(ctx, chain, token)
+		if err == nil {
+			return price, nil
+		}
+		errs = append(errs, sourceError(s, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return Price{}, fallbackError(errs)
+}
+
+func sourceError(s TradeSource, err error) error {
+	if !errors.Is(err, ErrNotSupported) {
+		fmt.Printf("Source %s failed: %v\n", s.Name(), err)
+	}
+	return fmt.Errorf("%s: %w", s.Name(), err)
+}
+
+func fallbackError(errs []error) error {
+	if len(errs) == 0 {
+		return ErrNotSupported
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,35 @@
+// Package sourcetest holds the assertions shared by the tests of the trade
+// sources.
+package sourcetest
+
+import (
+	"math"
+	"testing"
+
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+// AssertTrade reports the fields of got that differ from want. Amounts are
+// compared up to float rounding.
+func AssertTrade(t *testing.T, got pnlmodel.EventTrade, want pnlmodel.EventTrade) {
+	t.Helper()
+
+	if got.TxHash != want.TxHash || got.EventType != want.EventType || got.Counterparty != want.Counterparty || got.Timestamp != want.Timestamp {
+		t.Errorf("trade = %+v, want %+v", got, want)
+	}
+
+	for _, f := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"tokens", got.TokensAmount, want.TokensAmount},
+		{"quote", got.QuoteAmount, want.QuoteAmount},
+		{"quote usd", got.QuoteAmountUSD, want.QuoteAmountUSD},
+		{"price", got.Price, want.Price},
+		{"fee", got.Fee, want.Fee},
+	} {
+		if math.Abs(f.got-f.want) > 1e-9 {
+			t.Errorf("%s %s = %v, want %v", want.TxHash, f.name, f.got, f.want)
+		}
+	}
+}
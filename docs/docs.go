@@ -15,6 +15,172 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/jobs/{id}": {
+            "get": {
+                "description": "Returns the status of a scan queued through /api/pnl, or of a batch scan job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get a job status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.JobStatusResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/pnl/{chain}/{wallet}": {
+            "get": {
+                "description": "Returns the stored scan of the wallet: the all-time one by default, the latest windowed one when scanDay is set. Only one windowed scan is kept per wallet, so scanDay selects the windowed scan rather than a window: any value above 0 returns it whatever window it covers, which is its scan-day. A scan stored before the chain-neutral schema is answered with 409 until the wallet is scanned again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pnl"
+                ],
+                "summary": "Get a wallet PnL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "sol",
+                        "description": "Chain ID",
+                        "name": "chain",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet address",
+                        "name": "wallet",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "0 for the all-time scan, above 0 for the latest windowed scan",
+                        "name": "scanDay",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pnlmodel.PNL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Queues a deep scan of the wallet over its last scanDay days, 0 for all time. The scan runs on the scan queue consumers; its status is at /api/jobs/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pnl"
+                ],
+                "summary": "Queue a wallet PnL scan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "sol",
+                        "description": "Chain ID",
+                        "name": "chain",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet address",
+                        "name": "wallet",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Scan window",
+                        "name": "scan",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/services.PNLScanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/services.PNLScanResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/wallettracker/add": {
             "post": {
                 "description": "Creates a task with a specified duration, priority, and timeout, and adds it to the worker pool",
@@ -51,12 +217,534 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/services.ErrorResponse"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/services.ErrorResponse"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "pnlmodel.EventTrade": {
+            "type": "object",
+            "properties": {
+                "counterparty": {
+                    "type": "string"
+                },
+                "date-time": {
+                    "type": "string"
+                },
+                "event-type": {
+                    "type": "string"
+                },
+                "fee": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "price-usd": {
+                    "type": "number"
+                },
+                "quote-amount": {
+                    "type": "number"
+                },
+                "quote-amount-usd": {
+                    "type": "number"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "tokens-amount": {
+                    "type": "number"
+                },
+                "tx-hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "pnlmodel.ExcludedToken": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "token-address": {
+                    "type": "string"
+                },
+                "token-symbol": {
+                    "type": "string"
+                }
+            }
+        },
+        "pnlmodel.LostXPNL": {
+            "type": "object",
+            "properties": {
+                "count-buy": {
+                    "type": "integer"
+                },
+                "count-sell": {
+                    "type": "integer"
+                },
+                "count-sell-actual": {
+                    "type": "integer"
+                },
+                "end-time": {
+                    "type": "string"
+                },
+                "price-quote-best-sell": {
+                    "type": "number"
+                },
+                "price-quote-first-buy": {
+                    "type": "number"
+                },
+                "profit-quote": {
+                    "type": "number"
+                },
+                "profit-quote-actual": {
+                    "type": "number"
+                },
+                "profit-quote-actual-net": {
+                    "type": "number"
+                },
+                "profit-quote-net": {
+                    "type": "number"
+                },
+                "profit-usd": {
+                    "type": "number"
+                },
+                "realized-profit-quote": {
+                    "type": "number"
+                },
+                "realized-profit-usd": {
+                    "type": "number"
+                },
+                "roi-usd": {
+                    "type": "number"
+                },
+                "start-time": {
+                    "type": "string"
+                },
+                "token-address": {
+                    "type": "string"
+                },
+                "token-hold-amount": {
+                    "type": "number"
+                },
+                "token-hold-cost-quote": {
+                    "type": "number"
+                },
+                "token-hold-cost-usd": {
+                    "type": "number"
+                },
+                "token-hold-quote-amount": {
+                    "type": "number"
+                },
+                "token-hold-usd-amount": {
+                    "type": "number"
+                },
+                "token-symbol": {
+                    "type": "string"
+                },
+                "total-fees": {
+                    "type": "number"
+                },
+                "total-fees-usd": {
+                    "type": "number"
+                },
+                "total-quote-buy": {
+                    "type": "number"
+                },
+                "total-quote-inflow-cost": {
+                    "type": "number"
+                },
+                "total-quote-outflow-cost": {
+                    "type": "number"
+                },
+                "total-quote-sell": {
+                    "type": "number"
+                },
+                "total-quote-sell-actual": {
+                    "type": "number"
+                },
+                "total-token-buy": {
+                    "type": "number"
+                },
+                "total-token-inflow": {
+                    "type": "number"
+                },
+                "total-token-outflow": {
+                    "type": "number"
+                },
+                "total-token-sell": {
+                    "type": "number"
+                },
+                "total-token-sell-actual": {
+                    "type": "number"
+                },
+                "total-usd-buy": {
+                    "type": "number"
+                },
+                "total-usd-sell": {
+                    "type": "number"
+                },
+                "unexplained-rate": {
+                    "type": "number"
+                },
+                "unexplained-token-amount": {
+                    "type": "number"
+                },
+                "unrealized-profit-quote": {
+                    "type": "number"
+                },
+                "unrealized-profit-usd": {
+                    "type": "number"
+                },
+                "xpnl": {
+                    "type": "number"
+                },
+                "xpnl-rate": {
+                    "type": "number"
+                },
+                "xpnl-rate-trade": {
+                    "type": "number"
+                },
+                "xpnl-trade": {
+                    "type": "number"
+                }
+            }
+        },
+        "pnlmodel.LotMatch": {
+            "type": "object",
+            "properties": {
+                "buy-time": {
+                    "type": "integer"
+                },
+                "buy-tx-hash": {
+                    "type": "string"
+                },
+                "cost": {
+                    "type": "number"
+                },
+                "cost-usd": {
+                    "type": "number"
+                },
+                "holding-time": {
+                    "type": "integer"
+                },
+                "proceeds": {
+                    "type": "number"
+                },
+                "proceeds-usd": {
+                    "type": "number"
+                },
+                "profit": {
+                    "type": "number"
+                },
+                "profit-usd": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "sell-time": {
+                    "type": "integer"
+                },
+                "sell-tx-hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "pnlmodel.PNL": {
+            "type": "object",
+            "properties": {
+                "chain": {
+                    "type": "string"
+                },
+                "cost-basis": {
+                    "type": "string"
+                },
+                "excluded-tokens": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnlmodel.ExcludedToken"
+                    }
+                },
+                "incomplete": {
+                    "description": "Incomplete is set when the scan was cancelled before every token was\nscanned.",
+                    "type": "boolean"
+                },
+                "lost-xpnl": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnlmodel.LostXPNL"
+                    }
+                },
+                "quote-asset": {
+                    "type": "string"
+                },
+                "scan-day": {
+                    "description": "ScanDay is the window of the scan in days, 0 for all time.",
+                    "type": "integer"
+                },
+                "summary-review": {
+                    "$ref": "#/definitions/pnlmodel.SummaryReview"
+                },
+                "trades": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnlmodel.TradeHistory"
+                    }
+                },
+                "wallet-address": {
+                    "type": "string"
+                },
+                "xpnl": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnlmodel.XPNL"
+                    }
+                }
+            }
+        },
+        "pnlmodel.SummaryReview": {
+            "type": "object",
+            "properties": {
+                "big-xpnl": {
+                    "type": "integer"
+                },
+                "rate-big-xpnl": {
+                    "type": "number"
+                },
+                "roi-usd": {
+                    "type": "number"
+                },
+                "total-fees": {
+                    "type": "number"
+                },
+                "total-lost": {
+                    "type": "integer"
+                },
+                "total-lost-usd": {
+                    "type": "integer"
+                },
+                "total-pnl-amount": {
+                    "type": "number"
+                },
+                "total-pnl-amount-actual": {
+                    "type": "number"
+                },
+                "total-pnl-amount-net": {
+                    "type": "number"
+                },
+                "total-pnl-amount-usd": {
+                    "type": "number"
+                },
+                "total-truncated": {
+                    "type": "integer"
+                },
+                "total-usd-buy": {
+                    "type": "number"
+                },
+                "total-win": {
+                    "type": "integer"
+                },
+                "total-win-usd": {
+                    "type": "integer"
+                },
+                "win-rate": {
+                    "type": "number"
+                },
+                "win-rate-usd": {
+                    "type": "number"
+                }
+            }
+        },
+        "pnlmodel.TradeHistory": {
+            "type": "object",
+            "properties": {
+                "end-time": {
+                    "type": "string"
+                },
+                "event-trades": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnlmodel.EventTrade"
+                    }
+                },
+                "lot-matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnlmodel.LotMatch"
+                    }
+                },
+                "mark-price-quote": {
+                    "description": "Mark prices used to value the tokens still held.",
+                    "type": "number"
+                },
+                "mark-price-usd": {
+                    "type": "number"
+                },
+                "start-time": {
+                    "type": "string"
+                },
+                "token-address": {
+                    "type": "string"
+                },
+                "token-symbol": {
+                    "type": "string"
+                },
+                "truncated": {
+                    "description": "Truncated is set when the source stopped before the first trade, so\nthe figures are computed from an incomplete history.",
+                    "type": "boolean"
+                },
+                "watermark": {
+                    "description": "Watermark is the Unix timestamp of the newest activity read from the\nsource. A rescan only fetches the activity from then on.",
+                    "type": "integer"
+                }
+            }
+        },
+        "pnlmodel.XPNL": {
+            "type": "object",
+            "properties": {
+                "count-buy": {
+                    "type": "integer"
+                },
+                "count-sell": {
+                    "type": "integer"
+                },
+                "count-sell-actual": {
+                    "type": "integer"
+                },
+                "end-time": {
+                    "type": "string"
+                },
+                "price-quote-best-sell": {
+                    "type": "number"
+                },
+                "price-quote-first-buy": {
+                    "type": "number"
+                },
+                "profit-quote": {
+                    "type": "number"
+                },
+                "profit-quote-actual": {
+                    "type": "number"
+                },
+                "profit-quote-actual-net": {
+                    "type": "number"
+                },
+                "profit-quote-net": {
+                    "type": "number"
+                },
+                "profit-usd": {
+                    "type": "number"
+                },
+                "realized-profit-quote": {
+                    "type": "number"
+                },
+                "realized-profit-usd": {
+                    "type": "number"
+                },
+                "roi-usd": {
+                    "type": "number"
+                },
+                "start-time": {
+                    "type": "string"
+                },
+                "token-address": {
+                    "type": "string"
+                },
+                "token-hold-amount": {
+                    "type": "number"
+                },
+                "token-hold-cost-quote": {
+                    "type": "number"
+                },
+                "token-hold-cost-usd": {
+                    "type": "number"
+                },
+                "token-hold-quote-amount": {
+                    "type": "number"
+                },
+                "token-hold-usd-amount": {
+                    "type": "number"
+                },
+                "token-symbol": {
+                    "type": "string"
+                },
+                "total-fees": {
+                    "type": "number"
+                },
+                "total-fees-usd": {
+                    "type": "number"
+                },
+                "total-quote-buy": {
+                    "type": "number"
+                },
+                "total-quote-inflow-cost": {
+                    "type": "number"
+                },
+                "total-quote-outflow-cost": {
+                    "type": "number"
+                },
+                "total-quote-sell": {
+                    "type": "number"
+                },
+                "total-quote-sell-actual": {
+                    "type": "number"
+                },
+                "total-token-buy": {
+                    "type": "number"
+                },
+                "total-token-inflow": {
+                    "type": "number"
+                },
+                "total-token-outflow": {
+                    "type": "number"
+                },
+                "total-token-sell": {
+                    "type": "number"
+                },
+                "total-token-sell-actual": {
+                    "type": "number"
+                },
+                "total-usd-buy": {
+                    "type": "number"
+                },
+                "total-usd-sell": {
+                    "type": "number"
+                },
+                "unexplained-rate": {
+                    "type": "number"
+                },
+                "unexplained-token-amount": {
+                    "type": "number"
+                },
+                "unrealized-profit-quote": {
+                    "type": "number"
+                },
+                "unrealized-profit-usd": {
+                    "type": "number"
+                },
+                "xpnl": {
+                    "type": "number"
+                },
+                "xpnl-rate": {
+                    "type": "number"
+                },
+                "xpnl-rate-trade": {
+                    "type": "number"
+                },
+                "xpnl-trade": {
+                    "type": "number"
+                }
+            }
+        },
         "services.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -65,6 +753,88 @@ const docTemplate = `{
                 }
             }
         },
+        "services.JobStatusResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "chain": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "integer"
+                },
+                "done": {
+                    "description": "Done, Failed and Total count the wallets of a batch job.",
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "Kind is deepscan for a queued scan, toptraders, topholders or rescan\nfor a batch job.",
+                    "type": "string",
+                    "example": "deepscan"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "scanDay": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Status is pending, running, done, failed or cancelled.",
+                    "type": "string",
+                    "example": "pending"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "integer"
+                },
+                "wallet": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.PNLScanRequest": {
+            "type": "object",
+            "properties": {
+                "scanDay": {
+                    "description": "ScanDay is the window of the scan in days, 0 for all time.",
+                    "type": "integer",
+                    "example": 30
+                }
+            }
+        },
+        "services.PNLScanResponse": {
+            "type": "object",
+            "properties": {
+                "chain": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "scanDay": {
+                    "type": "integer"
+                },
+                "wallet": {
+                    "type": "string"
+                }
+            }
+        },
         "services.WalletTrackerRequest": {
             "type": "object",
             "properties": {
@@ -96,12 +866,12 @@ const docTemplate = `{
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
+	Version:          "1.0",
 	Host:             "",
-	BasePath:         "",
+	BasePath:         "/",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "PNL Scan Tool API",
+	Description:      "Wallet PnL scans, scan jobs and wallet trackers.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
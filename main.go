@@ -1,17 +1,58 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"pnl-scan-tool/core/evmrpc"
+	gmgnai "pnl-scan-tool/core/gmgn.ai"
+	"pnl-scan-tool/core/solanarpc"
+	"pnl-scan-tool/core/source"
 	_ "pnl-scan-tool/docs"
+	"pnl-scan-tool/package/cache"
+	"pnl-scan-tool/package/chains"
 	"pnl-scan-tool/package/configs"
+	"pnl-scan-tool/package/transport"
 	"pnl-scan-tool/platform/database/mongodb"
+	"pnl-scan-tool/src/handlers"
+	"pnl-scan-tool/src/pnl"
 	"pnl-scan-tool/src/services"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
 )
 
+// @title PNL Scan Tool API
+// @version 1.0
+// @description Wallet PnL scans, scan jobs and wallet trackers.
+// @BasePath /
 func main() {
+	// Serve recorded RPC responses as a local node stand-in: rpcreplay <sol|evm> <dir> <addr>
+	if len(os.Args) == 5 && os.Args[1] == "rpcreplay" {
+		dir := os.Args[3]
+		addr := os.Args[4]
+
+		handler := solanarpc.ReplayHandler(dir)
+
+		if os.Args[2] == "evm" {
+			handler = evmrpc.ReplayHandler(dir)
+		}
+
+		fmt.Println("Replaying", os.Args[2], "RPC from", dir, "on", addr)
+
+		if err := http.ListenAndServe(addr, handler); err != nil {
+			fmt.Println("Error:", err)
+		}
+
+		return
+	}
+
 	var env, err = configs.LoadConfig(".")
 
 	if err != nil {
@@ -33,9 +74,181 @@ func main() {
 
 	defer mongodb.Shutdown()
 
-	if len(os.Args) == 3 && os.Args[1] == "rescan" {
+	// Requests per second per host, e.g. "gmgn.ai=2,api.dexscreener.com=5:10"
+	limits, err := transport.ParseLimits(env.HTTP_RATE_LIMITS)
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for host, limit := range limits {
+		transport.Limits[host] = limit
+	}
+
+	defer transport.PrintStats()
+
+	// Record every HTTP response to a cassette directory, or replay one offline
+	switch {
+	case env.HTTP_RECORD != "" && env.HTTP_REPLAY != "":
+		fmt.Println("Error: HTTP_RECORD and HTTP_REPLAY cannot both be set")
+		return
+	case env.HTTP_RECORD != "":
+		transport.UseCassette(&transport.Cassette{Dir: env.HTTP_RECORD})
+	case env.HTTP_REPLAY != "":
+		fmt.Println("Replaying HTTP responses from", env.HTTP_REPLAY)
+		transport.UseCassette(&transport.Cassette{Dir: env.HTTP_REPLAY, Replay: true})
+	}
+
+	// Cache provider responses in "mongo" or on "disk", e.g. to rescan wallets
+	switch env.RESPONSE_CACHE {
+	case "":
+	case "mongo":
+		cache.Use(cache.MongoStore{Collection: "response_cache"})
+	case "disk":
+		cacheDir := env.RESPONSE_CACHE_DIR
+
+		if cacheDir == "" {
+			cacheDir = "cache"
+		}
+
+		cache.Use(cache.DiskStore{Dir: cacheDir})
+	default:
+		fmt.Println("Error: unknown response cache:", env.RESPONSE_CACHE)
+		return
+	}
+
+	// How long prices, holders and recent trades are cached, e.g. "price=1m,holders=30m"
+	ttls, err := cache.ParseTTLs(env.RESPONSE_CACHE_TTLS)
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for class, ttl := range ttls {
+		cache.TTLs[class] = ttl
+	}
+
+	defer cache.PrintStats()
+
+	// Wallets of a batch scanned at once, within the rate limits above
+	if env.SCAN_CONCURRENCY != 0 {
+		services.ScanConcurrency = env.SCAN_CONCURRENCY
+	}
+
+	// Activities read per token from gmgn.ai, 0 reads them all
+	gmgnai.MaxActivities = env.GMGN_MAX_ACTIVITIES
+
+	if env.SOLANA_RPC_URL != "" {
+		solanaSource := solanarpc.NewSource(env.SOLANA_RPC_URL)
+		solanaSource.Client.RecordDir = env.SOLANA_RPC_RECORD
+
+		if env.SOLANA_RPC_MAX_SIGNATURES != 0 {
+			solanaSource.MaxSignatures = env.SOLANA_RPC_MAX_SIGNATURES
+		}
+
+		services.RegisterSource("solanarpc", solanaSource)
+	}
+
+	// One RPC endpoint per EVM chain, e.g. "eth=https://...,base=https://..."
+	if env.EVM_RPC_URLS != "" {
+		var evmSources source.Fallback
+
+		for _, pair := range strings.Split(env.EVM_RPC_URLS, ",") {
+			chainID, endpoint, _ := strings.Cut(strings.TrimSpace(pair), "=")
+
+			chain, err := chains.Get(chainID)
+
+			if err != nil || chain.Family != chains.EVM {
+				fmt.Println("Error: not an EVM chain:", chainID)
+				return
+			}
+
+			evmSource := evmrpc.NewSource(chain, endpoint)
+			evmSource.Client.RecordDir = env.EVM_RPC_RECORD
+
+			evmSources = append(evmSources, evmSource)
+		}
+
+		services.RegisterSource("evmrpc", evmSources)
+
+		// Chains gmgn.ai does not cover, such as arb, are then scanned from
+		// their node by default
+		if env.TRADE_SOURCE == "" {
+			services.Source = source.Fallback{services.Source, evmSources}
+		}
+	}
+
+	// Trade sources tried in order, e.g. "gmgn,photon,dexscreener"
+	if env.TRADE_SOURCE != "" {
+		services.Source, err = services.NewSource(env.TRADE_SOURCE)
+
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+
+	// Cost basis of tokens received without a swap, e.g. "transfer_in=inherit,airdrop=zero"
+	services.DefaultOptions.InflowPolicies, err = pnl.ParseInflowPolicies(env.INFLOW_COST_BASIS)
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// Tokens left out of the scans, one <chain>.json per chain
+	exclusionsDir := env.EXCLUSIONS_DIR
+
+	if exclusionsDir == "" {
+		exclusionsDir = "exclusions"
+	}
+
+	if err := chains.LoadExclusions(exclusionsDir); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	services.CountStableRotations = env.COUNT_STABLE_ROTATIONS
+
+	// Named rescan schedules, saved to MongoDB by the scheduler
+	schedulesFile := env.SCHEDULES_FILE
+
+	if schedulesFile == "" {
+		schedulesFile = "schedules.json"
+	}
+
+	// Ctrl-C stops the running scan, which saves what it has as incomplete
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Resume a rescan, topholder or toptrader job from its checkpoint
+	if len(os.Args) == 3 && os.Args[1] == "--resume" {
+		services.ResumeJob(ctx, os.Args[2])
+	}
+
+	if (len(os.Args) == 3 || len(os.Args) == 4) && os.Args[1] == "rescan" {
 		chain := os.Args[2]
-		services.ReScanWalletPNLJob(chain)
+		filter := bson.M{}
+		incremental := true
+
+		// Rescans only fetch new trades, "full" rescans every wallet from
+		// scratch and "backfill-usd" the wallets stored without USD figures
+		if len(os.Args) == 4 {
+			switch os.Args[3] {
+			case "full":
+				incremental = false
+			case "backfill-usd":
+				filter = services.BackfillUSDFilter
+				incremental = false
+			default:
+				fmt.Println("Error: unknown rescan mode:", os.Args[3])
+				return
+			}
+		}
+
+		services.ReScanWalletPNLJob(ctx, chain, filter, incremental)
 	}
 
 	if len(os.Args) == 4 && os.Args[1] == "topholder" {
@@ -43,7 +256,7 @@ func main() {
 		tokenAddress := os.Args[3]
 
 		// Call the PNLScan function with the parsed arguments
-		services.TopHoldersScan(chain, tokenAddress)
+		services.TopHoldersScan(ctx, chain, tokenAddress)
 	}
 
 	if len(os.Args) == 4 && os.Args[1] == "toptrader" {
@@ -51,10 +264,10 @@ func main() {
 		tokenAddress := os.Args[3]
 
 		// Call the PNLScan function with the parsed arguments
-		services.TopTraderScan(chain, tokenAddress)
+		services.TopTraderScan(ctx, chain, tokenAddress)
 	}
 
-	if len(os.Args) == 5 && os.Args[1] == "deepscan" {
+	if (len(os.Args) == 5 || len(os.Args) == 6) && os.Args[1] == "deepscan" {
 		chain := os.Args[2]
 		// Get arguments
 		address := os.Args[3]
@@ -68,16 +281,94 @@ func main() {
 			return
 		}
 
-		if chain == "sol" {
-			// Call the PNLScan function with the parsed arguments
-			services.DeepPNLScanSol(chain, address, number)
+		options := services.DefaultOptions
+
+		// Optional cost basis: fifo (default), lifo or average
+		if len(os.Args) == 6 {
+			options.CostBasis, err = pnl.ParseCostBasis(os.Args[5])
+
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+		}
+
+		// Call the DeepPNLScan function with the parsed arguments
+		if _, err := services.DeepPNLScan(ctx, chain, address, number, options); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+
+	// Queue a deep scan for any process running "worker" to pick up
+	if len(os.Args) == 5 && os.Args[1] == "enqueue" {
+		scanDay, err := strconv.Atoi(os.Args[4])
+
+		if err != nil {
+			fmt.Println("Error: third argument should be a number")
+			return
+		}
+
+		message, err := services.EnqueueScan(ctx, os.Args[2], os.Args[3], scanDay)
+
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+
+		fmt.Println("Queued scan:", message.ID)
+	}
+
+	// Run queued scans until Ctrl-C, alongside any other worker
+	if len(os.Args) == 2 && os.Args[1] == "worker" {
+		services.ConsumeScans(ctx)
+	}
+
+	// Run the stored schedules until Ctrl-C, e.g. a rescan every 6h
+	if len(os.Args) == 2 && os.Args[1] == "scheduler" {
+		if err := services.LoadSchedules(ctx, schedulesFile); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+
+		services.RunScheduler(ctx)
+	}
+
+	// List the schedules with how their last run went
+	if len(os.Args) == 2 && os.Args[1] == "schedules" {
+		schedules, err := services.ListSchedules(ctx)
+
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+
+		for _, schedule := range schedules {
+			lastRun := "never"
+
+			if schedule.LastRun != 0 {
+				lastRun = time.Unix(schedule.LastRun, 0).Format(time.DateTime)
+			}
+
+			fmt.Printf("%s | %s | %s %s %s | enabled: %v | last run: %s (%ds) %s %s\n", schedule.Name, schedule.Spec, schedule.Action, schedule.Chain, schedule.Mode, schedule.Enabled, lastRun, schedule.LastDuration, schedule.LastOutcome, schedule.LastError)
+		}
+	}
+
+	// Run the HTTP API with the queue consumer and the scheduler until Ctrl-C
+	if len(os.Args) == 2 && os.Args[1] == "serve" {
+		if err := services.LoadSchedules(ctx, schedulesFile); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+
+		port := env.SERVER_PORT
 
-		} else if chain == "eth" {
-			// Call the PNLScan function with the parsed arguments
-			services.DeepPNLScanETH(chain, address, number)
+		if port == "" {
+			port = "8002"
+		}
 
-		} else {
-			fmt.Println("Error: chain not supported")
+		if err := handlers.Serve(ctx, ":"+port, env.TELEGRAM_BOT_TOKEN); err != nil {
+			fmt.Println("Error:", err)
 			return
 		}
 	}
@@ -99,17 +390,4 @@ func main() {
 	// 	// Call the PNLScan function with the parsed arguments
 	// 	services.PNLScan(address, number)
 	// }
-
-	// app := fiber.New()
-
-	// // Initialize the Task Manager
-	// taskManager := services.NewWalletTrackerTaskManager(4, 10, 2*time.Second)
-
-	// // Serve Swagger UI
-	// app.Get("/swagger/*", swagger.HandlerDefault) // Swagger endpoint
-
-	// handlers.WalletTrackerRoutes(app, taskManager)
-
-	// // Start the server
-	// app.Listen(":3000")
 }
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Class groups the responses that stay valid for as long as each other.
+type Class string
+
+const (
+	// History is a page behind a cursor whose trades are old enough to not
+	// change anymore. Cursors point at the last trade of the previous page, so
+	// later trades never shift such a page. It is still only kept for a day so
+	// that a provider correcting past trades is picked up.
+	History Class = "history"
+	// Recent is the latest trades of a wallet, which grow as it trades.
+	Recent Class = "recent"
+	// Price is a token price or pair quote.
+	Price Class = "price"
+	// Holders is a list of top holders or top traders of a token.
+	Holders Class = "holders"
+)
+
+// TTLs is how long the responses of each class are served from the cache. 0
+// keeps them forever.
+var TTLs = map[Class]time.Duration{
+	History: 24 * time.Hour,
+	Recent:  10 * time.Minute,
+	Price:   30 * time.Second,
+	Holders: 10 * time.Minute,
+}
+
+// Settled is how old a trade must be before the page holding it is cached as
+// History.
+var Settled = 10 * time.Minute
+
+// Entry is a cached response body and when it was stored.
+type Entry struct {
+	Data     []byte
+	StoredAt time.Time
+}
+
+// Store keeps entries by key, on disk or in MongoDB.
+type Store interface {
+	Get(ctx context.Context, key string) (Entry, bool, error)
+	Set(ctx context.Context, key string, entry Entry) error
+}
+
+var (
+	storeMu sync.RWMutex
+	store   Store
+
+	hits   atomic.Int64
+	misses atomic.Int64
+)
+
+// Use caches responses in s. nil turns the cache off.
+func Use(s Store) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+func activeStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+
+	return store
+}
+
+// Key normalizes a request URL so that the same query always maps to the same
+// entry, whatever the order of its parameters.
+func Key(rawURL string) string {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.ReplaceAll(u.Path, "//", "/")
+	u.RawQuery = u.Query().Encode()
+	u.Fragment = ""
+
+	return u.String()
+}
+
+// Get returns the response cached for rawURL if it is still fresh for class.
+// A failing store counts as a miss.
+func Get(ctx context.Context, class Class, rawURL string) ([]byte, bool) {
+	s := activeStore()
+
+	if s == nil {
+		return nil, false
+	}
+
+	entry, ok, err := s.Get(ctx, Key(rawURL))
+
+	if err != nil {
+		fmt.Println("Error reading response cache:", err)
+	}
+
+	if !ok || err != nil || expired(class, entry) {
+		misses.Add(1)
+		return nil, false
+	}
+
+	hits.Add(1)
+
+	return entry.Data, true
+}
+
+// Set caches the response to rawURL. Callers set it only once the response
+// decoded, so that a broken page is fetched again next time.
+func Set(ctx context.Context, class Class, rawURL string, data []byte) {
+	s := activeStore()
+
+	if s == nil {
+		return
+	}
+
+	if err := s.Set(ctx, Key(rawURL), Entry{Data: data, StoredAt: time.Now()}); err != nil {
+		fmt.Println("Error writing response cache:", err)
+	}
+}
+
+// IsSettled reports whether a trade made at timestamp, in Unix seconds, can no
+// longer change.
+func IsSettled(timestamp int64) bool {
+	return time.Since(time.Unix(timestamp, 0)) > Settled
+}
+
+func expired(class Class, entry Entry) bool {
+	ttl := TTLs[class]
+
+	return ttl != 0 && time.Since(entry.StoredAt) > ttl
+}
+
+// ParseTTLs parses class lifetimes such as "price=1m,holders=30m".
+func ParseTTLs(s string) (map[Class]time.Duration, error) {
+	ttls := make(map[Class]time.Duration)
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+
+		if !ok {
+			return nil, fmt.Errorf("invalid cache ttl %q, expected class=duration", pair)
+		}
+
+		class := Class(strings.TrimSpace(name))
+
+		if _, ok := TTLs[class]; !ok {
+			return nil, fmt.Errorf("unknown cache class %q", name)
+		}
+
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid ttl for %s: %q", name, value)
+		}
+
+		ttls[class] = ttl
+	}
+
+	return ttls, nil
+}
+
+// PrintStats prints how many responses were served from the cache.
+func PrintStats() {
+	if activeStore() == nil {
+		return
+	}
+
+	fmt.Printf("Response cache | Hits: %d | Misses: %d\n", hits.Load(), misses.Load())
+}
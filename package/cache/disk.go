@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// DiskStore keeps one file per entry in Dir, named after a hash of its key.
+// The file's modification time is when the entry was stored.
+type DiskStore struct {
+	Dir string
+}
+
+func (d DiskStore) Get(ctx context.Context, key string) (Entry, bool, error) {
+	path := d.path(key)
+
+	info, err := os.Stat(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return Entry{}, false, nil
+	}
+
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	return Entry{Data: data, StoredAt: info.ModTime()}, true, nil
+}
+
+func (d DiskStore) Set(ctx context.Context, key string, entry Entry) error {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return err
+	}
+
+	path := d.path(key)
+
+	// Write then rename, so that a concurrent Get never reads half a file.
+	// Each write has its own temporary file, so concurrent Sets of a key
+	// don't write over each other's.
+	tmp, err := os.CreateTemp(d.Dir, filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(entry.Data)
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Chtimes(tmp.Name(), entry.StoredAt, entry.StoredAt); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d DiskStore) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+
+	return filepath.Join(d.Dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"pnl-scan-tool/platform/database/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MongoStore keeps entries in a MongoDB collection, keyed by _id.
+type MongoStore struct {
+	Collection string
+}
+
+func (m MongoStore) Get(ctx context.Context, key string) (Entry, bool, error) {
+	documents, err := mongodb.FindDocuments(ctx, m.Collection, bson.M{"_id": key}, 1, nil)
+
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	if len(documents) == 0 {
+		return Entry{}, false, nil
+	}
+
+	doc := documents[0]
+	data, _ := doc["data"].(string)
+	storedAt, ok := doc["storedat"].(int64)
+
+	if !ok {
+		return Entry{}, false, nil
+	}
+
+	return Entry{Data: []byte(data), StoredAt: time.Unix(storedAt, 0)}, true, nil
+}
+
+func (m MongoStore) Set(ctx context.Context, key string, entry Entry) error {
+	update := bson.M{"$set": bson.M{
+		"data":     string(entry.Data),
+		"storedat": entry.StoredAt.Unix(),
+	}}
+
+	return mongodb.UpsertOne(ctx, m.Collection, bson.M{"_id": key}, update)
+}
@@ -0,0 +1,258 @@
+package chains
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Family groups chains that share an address format and RPC interface.
+type Family string
+
+const (
+	Solana Family = "solana"
+	EVM    Family = "evm"
+)
+
+// Chain describes what the scans need to know about a chain.
+type Chain struct {
+	// ID is the chain argument of the CLI and the suffix of its collections.
+	ID     string
+	Family Family
+	// QuoteAsset is the native coin every PnL figure is expressed in.
+	QuoteAsset string
+	// WrappedNative is the token trades in the native coin are made with.
+	WrappedNative string
+	// QuoteAddresses are the addresses trades in the native coin are quoted
+	// with by data providers, the wrapped native token first.
+	QuoteAddresses []string
+	// Stablecoins are the dollar tokens trades can be quoted in.
+	Stablecoins []string
+	// Exclusions are the tokens not scanned as traded tokens. They default
+	// to the quote tokens and stablecoins and are replaced by LoadExclusions.
+	Exclusions []Exclusion
+	// BlockSeconds is the average block time.
+	BlockSeconds float64
+	// StartBlock is the first block a DEX decoded by the RPC sources was
+	// deployed at. All-time RPC scans of an EVM chain start there.
+	StartBlock uint64
+	// NativeUSDPool is a Uniswap V2 style pair of the wrapped native token
+	// and a stablecoin. The RPC sources price the native coin of a trade
+	// from its reserves at the block of the trade.
+	NativeUSDPool string
+	// GMGNID and DexScreenerID are the chain ids of those providers, empty
+	// when the provider does not cover the chain.
+	GMGNID        string
+	DexScreenerID string
+}
+
+var registry = map[string]Chain{}
+
+func init() {
+	Register(Chain{
+		ID:            "sol",
+		Family:        Solana,
+		QuoteAsset:    "SOL",
+		WrappedNative: "So11111111111111111111111111111111111111112",
+		QuoteAddresses: []string{
+			"So11111111111111111111111111111111111111112",
+			"So11111111111111111111111111111111111111111",
+		},
+		Stablecoins: []string{
+			"EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", // USDC
+			"Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB", // USDT
+		},
+		BlockSeconds:  0.4,
+		GMGNID:        "sol",
+		DexScreenerID: "solana",
+	})
+
+	Register(Chain{
+		ID:             "eth",
+		Family:         EVM,
+		QuoteAsset:     "ETH",
+		WrappedNative:  "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2",
+		QuoteAddresses: []string{"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"},
+		Stablecoins: []string{
+			"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", // USDC
+			"0xdac17f958d2ee523a2206206994597c13d831ec7", // USDT
+			"0x6b175474e89094c44da98b954eedeac495271d0f", // DAI
+		},
+		BlockSeconds: 12,
+		StartBlock:   10000835, // Uniswap V2 factory
+		// Uniswap V2 USDC/WETH
+		NativeUSDPool: "0xb4e16d0168e52d35cacd2c6185b44281ec28c9dc",
+		GMGNID:        "eth",
+		DexScreenerID: "ethereum",
+	})
+
+	Register(Chain{
+		ID:             "base",
+		Family:         EVM,
+		QuoteAsset:     "ETH",
+		WrappedNative:  "0x4200000000000000000000000000000000000006",
+		QuoteAddresses: []string{"0x4200000000000000000000000000000000000006"},
+		Stablecoins: []string{
+			"0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", // USDC
+			"0xd9aaec86b65d86f6a7b5b1b0c42ffa531710b6ca", // USDbC
+			"0x50c5725949a6f0c72e6c4a641f24049a917db0cb", // DAI
+		},
+		BlockSeconds: 2,
+		StartBlock:   1371680, // Uniswap V3 factory
+		// Uniswap V2 WETH/USDC
+		NativeUSDPool: "0x88a43bbdf9d098eec7bceda4e2494615dfd9bb9c",
+		GMGNID:        "base",
+		DexScreenerID: "base",
+	})
+
+	Register(Chain{
+		ID:             "bsc",
+		Family:         EVM,
+		QuoteAsset:     "BNB",
+		WrappedNative:  "0xbb4cdb9cbd36b01bd1cbaebf2de08d9173bc095c",
+		QuoteAddresses: []string{"0xbb4cdb9cbd36b01bd1cbaebf2de08d9173bc095c"},
+		Stablecoins: []string{
+			"0x55d398326f99059ff775485246999027b3197955", // USDT
+			"0x8ac76a51cc950d9822d68b83fe1ad97b32cd580d", // USDC
+			"0xe9e7cea3dedca5984780bafc599bd69add087d56", // BUSD
+		},
+		BlockSeconds: 3,
+		StartBlock:   586851, // PancakeSwap V1 factory
+		// PancakeSwap V2 WBNB/BUSD
+		NativeUSDPool: "0x58f876857a02d6762e0101bb5c46a8c1ed44dc16",
+		GMGNID:        "bsc",
+		DexScreenerID: "bsc",
+	})
+
+	// gmgn.ai does not cover Arbitrum, its trades are read from a node set
+	// in EVM_RPC_URLS
+	Register(Chain{
+		ID:             "arb",
+		Family:         EVM,
+		QuoteAsset:     "ETH",
+		WrappedNative:  "0x82af49447d8a07e3bd95bd0d56f35241523fbab1",
+		QuoteAddresses: []string{"0x82af49447d8a07e3bd95bd0d56f35241523fbab1"},
+		Stablecoins: []string{
+			"0xaf88d065e77c8cc2239327c5edb3a432268e5831", // USDC
+			"0xff970a61a04b1ca14834a43f5de4533ebddb5cc8", // USDC.e
+			"0xfd086bc7cd5c481dcc9c85ebe478a1c0b69fcbb9", // USDT
+		},
+		BlockSeconds: 0.25,
+		StartBlock:   165, // Uniswap V3 factory
+		// SushiSwap WETH/USDC.e
+		NativeUSDPool: "0x905dfcd5649217c42684f23958568e533c711aa3",
+		DexScreenerID: "arbitrum",
+	})
+}
+
+// Register adds a chain to the registry, replacing any chain with its ID.
+func Register(chain Chain) {
+	if chain.Exclusions == nil {
+		chain.Exclusions = defaultExclusions(chain)
+	}
+
+	registry[chain.ID] = chain
+}
+
+// Get returns the chain registered under id.
+func Get(id string) (Chain, error) {
+	chain, ok := registry[strings.ToLower(id)]
+
+	if !ok {
+		return Chain{}, fmt.Errorf("chain not supported: %s", id)
+	}
+
+	return chain, nil
+}
+
+// IDs returns the ids of all registered chains, sorted.
+func IDs() []string {
+	ids := make([]string, 0, len(registry))
+	for id := range registry {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// NormalizeAddress returns the canonical form of an address on the chain.
+// EVM addresses are case-insensitive and stored lower case, Solana addresses
+// are base58 and kept as is.
+func (c Chain) NormalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+
+	if c.Family == EVM {
+		return strings.ToLower(address)
+	}
+
+	return address
+}
+
+// ValidAddress reports whether address is well formed on the chain: 0x and 40
+// hex digits on EVM chains, 32 to 44 base58 characters on Solana.
+func (c Chain) ValidAddress(address string) bool {
+	address = strings.TrimSpace(address)
+
+	if c.Family == EVM {
+		if len(address) != 42 || !strings.HasPrefix(strings.ToLower(address), "0x") {
+			return false
+		}
+
+		_, err := hex.DecodeString(address[2:])
+
+		return err == nil
+	}
+
+	if len(address) < 32 || len(address) > 44 {
+		return false
+	}
+
+	for _, r := range address {
+		if !strings.ContainsRune(base58Alphabet, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// IsQuote reports whether address is one of the chain's quote addresses.
+func (c Chain) IsQuote(address string) bool {
+	return c.contains(c.QuoteAddresses, address)
+}
+
+// IsStablecoin reports whether address is one of the chain's stablecoins.
+func (c Chain) IsStablecoin(address string) bool {
+	return c.contains(c.Stablecoins, address)
+}
+
+// AllTimeCollection is where all-time wallet scans of the chain are stored.
+func (c Chain) AllTimeCollection() string {
+	return "all_time_pnl_wallet_" + c.ID
+}
+
+// DayCollection is where windowed wallet scans of the chain are stored.
+func (c Chain) DayCollection() string {
+	return "30_day_pnl_wallet_" + c.ID
+}
+
+// TokenScanCollection is where the tokens scanned for top traders or top
+// holders are stored.
+func (c Chain) TokenScanCollection() string {
+	return "token_scan_" + c.ID
+}
+
+func (c Chain) contains(addresses []string, address string) bool {
+	address = c.NormalizeAddress(address)
+
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+
+	return false
+}
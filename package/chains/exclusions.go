@@ -0,0 +1,92 @@
+package chains
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Category is why a token is not scanned as a traded token.
+type Category string
+
+const (
+	Stablecoin    Category = "stablecoin"
+	LiquidStaking Category = "lst"
+	WrappedNative Category = "wrapped_native"
+	BlueChip      Category = "blue_chip"
+)
+
+// Exclusion is a token skipped by the scans of a chain.
+type Exclusion struct {
+	Address  string   `json:"address"`
+	Symbol   string   `json:"symbol"`
+	Category Category `json:"category"`
+}
+
+// exclusionFile is the layout of <chain id>.json in the exclusions directory.
+type exclusionFile struct {
+	Tokens []Exclusion `json:"tokens"`
+}
+
+// LoadExclusions replaces the exclusions of every registered chain that has a
+// <chain id>.json file in dir. Chains without a file keep their defaults, the
+// quote tokens and stablecoins.
+func LoadExclusions(dir string) error {
+	for id, chain := range registry {
+		data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var file exclusionFile
+
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse exclusions of %s: %w", id, err)
+		}
+
+		for i := range file.Tokens {
+			file.Tokens[i].Address = chain.NormalizeAddress(file.Tokens[i].Address)
+		}
+
+		chain.Exclusions = file.Tokens
+		registry[id] = chain
+	}
+
+	return nil
+}
+
+// defaultExclusions excludes the quote tokens and stablecoins of a chain.
+func defaultExclusions(chain Chain) []Exclusion {
+	var exclusions []Exclusion
+
+	for _, address := range chain.QuoteAddresses {
+		exclusions = append(exclusions, Exclusion{Address: address, Symbol: "W" + chain.QuoteAsset, Category: WrappedNative})
+	}
+
+	for _, address := range chain.Stablecoins {
+		exclusions = append(exclusions, Exclusion{Address: address, Category: Stablecoin})
+	}
+
+	return exclusions
+}
+
+// Exclusion returns why address is not scanned as a traded token.
+func (c Chain) Exclusion(address string) (Exclusion, bool) {
+	address = c.NormalizeAddress(address)
+
+	for _, exclusion := range c.Exclusions {
+		if exclusion.Address == address {
+			return exclusion, true
+		}
+	}
+
+	return Exclusion{}, false
+}
@@ -7,14 +7,32 @@ import (
 )
 
 type Config struct {
-	DB_HOST            string `mapstructure:"DB_HOST"`
-	DB_PORT            string `mapstructure:"DB_PORT"`
-	DB_USER            string `mapstructure:"DB_USER"`
-	DB_PASSWORD        string `mapstructure:"DB_PASSWORD"`
-	DB_NAME            string `mapstructure:"DB_NAME"`
-	SERVER_PORT        string `mapstructure:"SERVER_PORT"`
-	TELEGRAM_BOT_TOKEN string `mapstructure:"TELEGRAM_BOT_TOKEN"`
-	CHANNEL_ID         int64  `mapstructure:"CHANNEL_ID"`
+	DB_HOST                   string `mapstructure:"DB_HOST"`
+	DB_PORT                   string `mapstructure:"DB_PORT"`
+	DB_USER                   string `mapstructure:"DB_USER"`
+	DB_PASSWORD               string `mapstructure:"DB_PASSWORD"`
+	DB_NAME                   string `mapstructure:"DB_NAME"`
+	SERVER_PORT               string `mapstructure:"SERVER_PORT"`
+	TELEGRAM_BOT_TOKEN        string `mapstructure:"TELEGRAM_BOT_TOKEN"`
+	CHANNEL_ID                int64  `mapstructure:"CHANNEL_ID"`
+	INFLOW_COST_BASIS         string `mapstructure:"INFLOW_COST_BASIS"`
+	TRADE_SOURCE              string `mapstructure:"TRADE_SOURCE"`
+	SOLANA_RPC_URL            string `mapstructure:"SOLANA_RPC_URL"`
+	SOLANA_RPC_RECORD         string `mapstructure:"SOLANA_RPC_RECORD"`
+	EVM_RPC_URLS              string `mapstructure:"EVM_RPC_URLS"`
+	EVM_RPC_RECORD            string `mapstructure:"EVM_RPC_RECORD"`
+	GMGN_MAX_ACTIVITIES       int    `mapstructure:"GMGN_MAX_ACTIVITIES"`
+	SOLANA_RPC_MAX_SIGNATURES int    `mapstructure:"SOLANA_RPC_MAX_SIGNATURES"`
+	EXCLUSIONS_DIR            string `mapstructure:"EXCLUSIONS_DIR"`
+	COUNT_STABLE_ROTATIONS    bool   `mapstructure:"COUNT_STABLE_ROTATIONS"`
+	HTTP_RATE_LIMITS          string `mapstructure:"HTTP_RATE_LIMITS"`
+	HTTP_RECORD               string `mapstructure:"HTTP_RECORD"`
+	HTTP_REPLAY               string `mapstructure:"HTTP_REPLAY"`
+	RESPONSE_CACHE            string `mapstructure:"RESPONSE_CACHE"`
+	RESPONSE_CACHE_DIR        string `mapstructure:"RESPONSE_CACHE_DIR"`
+	RESPONSE_CACHE_TTLS       string `mapstructure:"RESPONSE_CACHE_TTLS"`
+	SCAN_CONCURRENCY          int    `mapstructure:"SCAN_CONCURRENCY"`
+	SCHEDULES_FILE            string `mapstructure:"SCHEDULES_FILE"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
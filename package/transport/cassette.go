@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNotRecorded is returned in replay mode for a request missing from the
+// cassette.
+var ErrNotRecorded = errors.New("request not recorded")
+
+// Cassette is a directory of recorded responses, one file per request. In
+// record mode every response is written to it, in replay mode responses are
+// served from it and nothing reaches the network, so that a scan can be run
+// again on exactly the data it first saw.
+type Cassette struct {
+	Dir    string
+	Replay bool
+}
+
+// episode is one recorded request and its response.
+type episode struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Body       string      `json:"body,omitempty"`
+	StatusCode int         `json:"status-code"`
+	Header     http.Header `json:"header,omitempty"`
+	// Response is the response body, ResponseBase64 holds it instead when it
+	// is not text.
+	Response       string `json:"response,omitempty"`
+	ResponseBase64 string `json:"response-base64,omitempty"`
+}
+
+var (
+	cassetteMu sync.RWMutex
+	cassette   *Cassette
+)
+
+// UseCassette makes every Transport record to or replay from c. nil goes back
+// to the network.
+func UseCassette(c *Cassette) {
+	cassetteMu.Lock()
+	cassette = c
+	cassetteMu.Unlock()
+}
+
+func activeCassette() *Cassette {
+	cassetteMu.RLock()
+	defer cassetteMu.RUnlock()
+
+	return cassette
+}
+
+// replay serves the recorded response to req.
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	req, body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.Dir, episodeName(req, body)))
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, req.URL)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded episode
+
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("invalid recording of %s %s: %v", req.Method, req.URL, err)
+	}
+
+	response := []byte(recorded.Response)
+
+	if recorded.ResponseBase64 != "" {
+		response, err = base64.StdEncoding.DecodeString(recorded.ResponseBase64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording of %s %s: %v", req.Method, req.URL, err)
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header,
+		Body:          io.NopCloser(bytes.NewReader(response)),
+		ContentLength: int64(len(response)),
+		Request:       req,
+	}, nil
+}
+
+// record sends req through send and writes the response it gets to the
+// cassette before handing it back.
+func (c *Cassette) record(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	req, body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(response))
+
+	recorded := episode{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Body:       string(body),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+
+	if utf8.Valid(response) {
+		recorded.Response = string(response)
+	} else {
+		recorded.ResponseBase64 = base64.StdEncoding.EncodeToString(response)
+	}
+
+	if err := c.write(episodeName(req, body), recorded); err != nil {
+		fmt.Println("Error recording response:", err)
+	}
+
+	return resp, nil
+}
+
+func (c *Cassette) write(name string, recorded episode) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(c.Dir, name), data, 0644)
+}
+
+// readBody returns the body of req and a request that can still be sent.
+func readBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		defer reader.Close()
+
+		body, err := io.ReadAll(reader)
+
+		return req, body, err
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return clone, body, nil
+}
+
+// episodeName names the file of a request after its host and method and a
+// hash of its URL and body. Headers and cookies are left out, they change
+// between runs without changing the answer.
+func episodeName(req *http.Request, body []byte) string {
+	hash := sha1.New()
+	hash.Write([]byte(req.Method + " " + req.URL.String() + "\n"))
+	hash.Write(body)
+
+	host := strings.ReplaceAll(req.URL.Host, ":", "_")
+
+	return host + "_" + strings.ToLower(req.Method) + "_" + hex.EncodeToString(hash.Sum(nil)[:8]) + ".json"
+}
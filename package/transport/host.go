@@ -0,0 +1,175 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// BreakerFailures is how many 429, 5xx or failed requests in a row open the
+// circuit of a host.
+var BreakerFailures uint32 = 5
+
+// BreakerTimeout is how long an open circuit refuses requests before letting
+// one through to probe the host.
+var BreakerTimeout = 30 * time.Second
+
+// HostStats counts the requests made to one host.
+type HostStats struct {
+	Host          string
+	Requests      int           // Requests sent, retries included
+	Retries       int           // Requests sent again after a 429, 5xx or network error
+	Throttled     int           // Requests that waited for the rate limit or a Retry-After
+	ThrottledTime time.Duration // Time spent waiting for them
+	Rejected      int           // Requests refused while the circuit was open
+	Opened        int           // Times the circuit opened
+}
+
+// host is the budget, circuit breaker and stats shared by every request to one
+// host.
+type host struct {
+	bucket  *bucket
+	breaker *gobreaker.TwoStepCircuitBreaker
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+	stats       HostStats
+}
+
+var (
+	hostsMu sync.Mutex
+	hosts   = make(map[string]*host)
+)
+
+// lookup returns the shared state of name, creating it on first use.
+func lookup(name string) *host {
+	hostsMu.Lock()
+	defer hostsMu.Unlock()
+
+	if h, ok := hosts[name]; ok {
+		return h
+	}
+
+	limit := Limits[name]
+
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	h := &host{bucket: newBucket(limit), stats: HostStats{Host: name}}
+	h.breaker = gobreaker.NewTwoStepCircuitBreaker(gobreaker.Settings{
+		Name:    name,
+		Timeout: BreakerTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= BreakerFailures
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			fmt.Printf("Circuit %s: %s -> %s\n", name, from, to)
+
+			if to == gobreaker.StateOpen {
+				h.mu.Lock()
+				h.stats.Opened++
+				h.mu.Unlock()
+			}
+		},
+	})
+
+	hosts[name] = h
+
+	return h
+}
+
+// wait blocks until the host may be called again, honoring both its rate
+// limit and any Retry-After it sent.
+func (h *host) wait(ctx context.Context) error {
+	delay := h.bucket.reserve()
+
+	h.mu.Lock()
+	if paused := time.Until(h.pausedUntil); paused > delay {
+		delay = paused
+	}
+	h.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var err error
+
+	select {
+	case <-ctx.Done():
+		h.bucket.cancel()
+		err = ctx.Err()
+	case <-timer.C:
+	}
+
+	h.mu.Lock()
+	h.stats.Throttled++
+	h.stats.ThrottledTime += time.Since(start)
+	h.mu.Unlock()
+
+	return err
+}
+
+// pause holds back every request to the host for d.
+func (h *host) pause(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+}
+
+func (h *host) count(update func(stats *HostStats)) {
+	h.mu.Lock()
+	update(&h.stats)
+	h.mu.Unlock()
+}
+
+// Stats returns the stats of every host called so far, sorted by host.
+func Stats() []HostStats {
+	hostsMu.Lock()
+	defer hostsMu.Unlock()
+
+	stats := make([]HostStats, 0, len(hosts))
+
+	for _, h := range hosts {
+		h.mu.Lock()
+		stats = append(stats, h.stats)
+		h.mu.Unlock()
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Host < stats[j].Host
+	})
+
+	return stats
+}
+
+// PrintStats prints the requests, retries and throttled time of every host
+// called so far.
+func PrintStats() {
+	stats := Stats()
+
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Println("+++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++")
+
+	for _, s := range stats {
+		fmt.Printf("%s | Requests: %d | Retries: %d | Throttled: %d (%v) | Circuit Opened: %d | Rejected: %d\n",
+			s.Host, s.Requests, s.Retries, s.Throttled, s.ThrottledTime.Round(time.Millisecond), s.Opened, s.Rejected)
+	}
+
+	fmt.Println("+++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++")
+}
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is the request budget of one host.
+type Limit struct {
+	Rate  float64 // Requests per second, 0 is unlimited
+	Burst int     // Requests allowed at once after an idle period
+}
+
+// Limits holds the budget of each host. Hosts missing from it are not paced,
+// though they still get retries and a circuit breaker.
+var Limits = map[string]Limit{
+	"gmgn.ai":                 {Rate: 2, Burst: 2},
+	"photon-sol.tinyastro.io": {Rate: 1, Burst: 1},
+	"api.dexscreener.com":     {Rate: 5, Burst: 5},
+	"io.dexscreener.com":      {Rate: 2, Burst: 2},
+	"app.geckoterminal.com":   {Rate: 0.5, Burst: 1},
+	"api-v2.solscan.io":       {Rate: 1, Burst: 1},
+}
+
+// ParseLimits parses host budgets such as "gmgn.ai=2,api.dexscreener.com=5:10",
+// where the number after the colon is the burst.
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+
+		if pair == "" {
+			continue
+		}
+
+		host, value, ok := strings.Cut(pair, "=")
+
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q, expected host=rate", pair)
+		}
+
+		rateValue, burstValue, hasBurst := strings.Cut(value, ":")
+
+		rate, err := strconv.ParseFloat(rateValue, 64)
+
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %q", host, rateValue)
+		}
+
+		limit := Limit{Rate: rate, Burst: 1}
+
+		if hasBurst {
+			limit.Burst, err = strconv.Atoi(burstValue)
+
+			if err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid burst for %s: %q", host, burstValue)
+			}
+		}
+
+		limits[strings.TrimSpace(host)] = limit
+	}
+
+	return limits, nil
+}
+
+// bucket is a token bucket refilled at rate tokens per second.
+type bucket struct {
+	mu     sync.Mutex
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit) *bucket {
+	return &bucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// reserve takes a token and returns how long to wait before it may be used.
+func (b *bucket) reserve() time.Duration {
+	if b.limit.Rate <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	b.last = now
+
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+}
+
+// cancel gives back a token reserved by a request that was never sent.
+func (b *bucket) cancel() {
+	if b.limit.Rate <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.tokens++
+	b.mu.Unlock()
+}
@@ -0,0 +1,210 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// ErrCircuitOpen is returned for a request to a host whose circuit is open
+// after sustained 429, 5xx or network failures.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// MaxRetries is how many times a request is sent again after a 429, 5xx or
+// network error.
+var MaxRetries = 4
+
+// MaxBackoff caps the pause between two retries without a Retry-After.
+var MaxBackoff = time.Minute
+
+// MaxRetryAfter is the longest Retry-After honored, a host asking for more
+// gets its response back to the caller instead.
+var MaxRetryAfter = 5 * time.Minute
+
+// AttemptTimeout bounds one request and the read of its body, waits for the
+// rate limit excluded.
+var AttemptTimeout = 30 * time.Second
+
+// Transport is an http.RoundTripper that paces requests per host, retries
+// throttled and failed ones, and stops calling a host whose circuit is open.
+// Every Transport shares the same per-host budget, so concurrent scans do not
+// add up against one API, and the same cassette when one is in use.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// New wraps base, http.DefaultTransport when nil.
+func New(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c := activeCassette(); c != nil {
+		if c.Replay {
+			return c.replay(req)
+		}
+
+		return c.record(req, t.roundTrip)
+	}
+
+	return t.roundTrip(req)
+}
+
+// roundTrip sends req to the network within the budget of its host.
+func (t *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	h := lookup(req.URL.Hostname())
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := h.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.send(h, attemptReq)
+
+		if errors.Is(err, ErrCircuitOpen) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		if (err == nil && !retryable(resp.StatusCode)) || attempt >= MaxRetries {
+			return resp, err
+		}
+
+		wait := backoff(attempt)
+
+		if err == nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if retryAfter > MaxRetryAfter {
+					return resp, nil
+				}
+
+				wait = retryAfter
+			}
+
+			fmt.Printf("Received %d from %s, retrying in %v...\n", resp.StatusCode, req.URL.Host, wait)
+
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			fmt.Printf("Request to %s failed: %v, retrying in %v...\n", req.URL.Host, err, wait)
+		}
+
+		h.pause(wait)
+		h.count(func(stats *HostStats) { stats.Retries++ })
+	}
+}
+
+// send makes one attempt through the circuit breaker of h.
+func (t *Transport) send(h *host, req *http.Request) (*http.Response, error) {
+	done, err := h.breaker.Allow()
+
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		h.count(func(stats *HostStats) { stats.Rejected++ })
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, req.URL.Host)
+	}
+
+	h.count(func(stats *HostStats) { stats.Requests++ })
+
+	ctx, cancel := context.WithTimeout(req.Context(), AttemptTimeout)
+	resp, err := t.Base.RoundTrip(req.WithContext(ctx))
+
+	if err != nil {
+		cancel()
+		// A cancelled scan says nothing about the health of the host
+		done(req.Context().Err() != nil)
+		return nil, err
+	}
+
+	done(!retryable(resp.StatusCode))
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// rewind returns the request to send on attempt, with a fresh body on retries.
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot retry request to %s: body cannot be rewound", req.URL.Host)
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Body = body
+
+	return retry, nil
+}
+
+// retryable reports whether a response status is worth another try.
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// backoff is the pause before retry attempt+1: 1s, 2s, 4s... up to MaxBackoff.
+func backoff(attempt int) time.Duration {
+	wait := time.Second << attempt
+
+	if wait <= 0 || wait > MaxBackoff {
+		return MaxBackoff
+	}
+
+	return wait
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+
+		if wait < 0 {
+			wait = 0
+		}
+
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// cancelBody releases the attempt's timeout once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -14,6 +14,19 @@ func ConvertStringToFloat64(s string) float64 {
 	return value
 }
 
+// ConvertToFloat64 converts a JSON-decoded number or numeric string to a float64.
+// It returns 0 for any other value.
+func ConvertToFloat64(v interface{}) float64 {
+	switch value := v.(type) {
+	case float64:
+		return value
+	case string:
+		return ConvertStringToFloat64(value)
+	default:
+		return 0
+	}
+}
+
 // ConvertStringToInt converts a string to an int.
 // It returns the int value and an error if the conversion fails.
 func ConvertStringToInt(s string) int {
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep pauses for d, or until ctx is done, in which case it returns the
+// context's error.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"crypto/tls"
 	"net"
 	"net/http"
+	"pnl-scan-tool/package/transport"
 	"time"
 )
 
+// Client is shared by the API clients. Its transport paces and retries requests
+// per host and times out each attempt, so it has no overall timeout.
 var Client = &http.Client{
-	Timeout: 30 * time.Second, // Set a timeout for the entire request
-	Transport: &http.Transport{
+	Transport: transport.New(&http.Transport{
 		TLSClientConfig: &tls.Config{
 			MinVersion:               tls.VersionTLS12,
 			PreferServerCipherSuites: true, // Prioritize server's cipher suite order
@@ -35,5 +37,5 @@ var Client = &http.Client{
 		TLSHandshakeTimeout:   10 * time.Second,          // Set timeout for TLS handshake
 		ResponseHeaderTimeout: 10 * time.Second,          // Set a timeout for waiting on response headers
 		Proxy:                 http.ProxyFromEnvironment, // Use system-wide proxy settings
-	},
+	}),
 }
@@ -3,32 +3,138 @@ package workerpool
 import (
 	"container/heap"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 )
 
+// ErrDuplicateTask is returned for a task with the ID of one still queued or
+// running.
+var ErrDuplicateTask = errors.New("duplicate task")
+
 // Task represents a unit of work with priority and timeout.
 type Task struct {
+	// ID identifies the task in the pool, one is given when left empty.
+	ID         string
 	Job        func(ctx context.Context) error
 	Ctx        context.Context
 	CancelFunc context.CancelFunc
 	Priority   int
-	Timeout    time.Duration
-	CreatedAt  time.Time
+	// Timeout bounds each attempt of the task, 0 leaves it unbounded.
+	Timeout   time.Duration
+	Retry     RetryPolicy
+	CreatedAt time.Time
+
+	attempts int
+	queuedAt time.Time
+	value    interface{}
+	future   *Future
+}
+
+// RetryPolicy is how a failed task is run again.
+type RetryPolicy struct {
+	// MaxAttempts is how many times the task runs at most, 0 runs it once.
+	MaxAttempts int
+	// Backoff is the pause before the first retry, doubled for every next one
+	// up to MaxBackoff when set.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retryable, when set, reports whether an error is worth another attempt.
+	Retryable func(err error) bool
+}
+
+// Result is the outcome of a task once it succeeded, gave up or was cancelled.
+type Result struct {
+	TaskID   string
+	Value    interface{}
+	Err      error
+	Attempts int
+	// Latency is the time from when the task was added to when it finished,
+	// retries included.
+	Latency time.Duration
+}
+
+// Future is the result of a task to come.
+type Future struct {
+	done   chan struct{}
+	result Result
+}
+
+// Done is closed once the result is known.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result waits for the task and returns its result.
+func (f *Future) Result() Result {
+	<-f.done
+	return f.result
+}
+
+// Get waits for the task, or ctx, and returns its value.
+func (f *Future) Get(ctx context.Context) (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.result.Value, f.result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Report sums up the tasks run by a pool.
+type Report struct {
+	Completed int
+	Failed    int
+	Cancelled int
+	Retried   int
+	// DeadLetters are the tasks that failed on their last attempt.
+	DeadLetters []Result
 }
 
 // NewTask creates a new Task with context, priority, and timeout.
-func NewTask(job func(ctx context.Context) error, priority int, timeout time.Duration) *Task {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+func NewTask(id string, job func(ctx context.Context) error, priority int, timeout time.Duration) *Task {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Task{
+		ID:         id,
 		Job:        job,
 		Ctx:        ctx,
 		CancelFunc: cancel,
 		Priority:   priority,
 		Timeout:    timeout,
 		CreatedAt:  time.Now(),
+		future:     &Future{done: make(chan struct{})},
+	}
+}
+
+// NewValueTask creates a Task whose future holds the value returned by fn.
+func NewValueTask(id string, fn func(ctx context.Context) (interface{}, error), priority int, timeout time.Duration) *Task {
+	task := NewTask(id, nil, priority, timeout)
+	task.Job = func(ctx context.Context) error {
+		value, err := fn(ctx)
+		task.value = value
+		return err
 	}
+	return task
+}
+
+// Future returns the result of the task to come.
+func (t *Task) Future() *Future {
+	if t.future == nil {
+		t.future = &Future{done: make(chan struct{})}
+	}
+	return t.future
+}
+
+// run makes one attempt of the task.
+func (t *Task) run() error {
+	ctx := t.Ctx
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	return t.Job(ctx)
 }
 
 // priorityQueue implements heap.Interface and holds Tasks.
@@ -70,72 +176,138 @@ type WorkerPool struct {
 	ctx             context.Context
 	cancelFunc      context.CancelFunc
 	metrics         *Metrics
-	shutdownCh      chan struct{}
-	workerSem       chan struct{}
 	lastScaleTime   time.Time
 	cooldownPeriod  time.Duration
 	tasksAdded      int
 	tasksCompleted  int
+	nextID          int
 
 	taskMap map[string]struct{} // Map to track task uniqueness
+
+	// taskCond wakes idle workers when a task is queued, when workers are to
+	// retire and when the pool shuts down. It guards, with taskLock, the
+	// worker counts: retiring is how many idle workers are to exit, busy how
+	// many are running a task
+	taskCond   *sync.Cond
+	retiring   int
+	busy       int
+	nextWorker int
+
+	// pending counts the tasks added and not finished yet, idle is signalled
+	// when it drops to zero
+	pending int
+	idle    *sync.Cond
+	report  Report
 }
 
 // Metrics holds statistics about the worker pool's performance.
 type Metrics struct {
 	TasksCompleted        int64
 	TasksFailed           int64
+	TasksRetried          int64
 	AverageWaitTime       time.Duration
 	AverageProcessingTime time.Duration
+	AverageLatency        time.Duration
+	MaxLatency            time.Duration
+	MaxQueueDepth         int
 	mu                    sync.Mutex
+
+	waits    int64
+	attempts int64
+	finished int64
 }
 
 // MetricsSnapshot represents a snapshot of the metrics without the mutex.
 type MetricsSnapshot struct {
 	TasksCompleted        int64
 	TasksFailed           int64
+	TasksRetried          int64
 	AverageWaitTime       time.Duration
 	AverageProcessingTime time.Duration
+	// AverageLatency and MaxLatency are from when a task is added to when it
+	// finishes, retries included.
+	AverageLatency time.Duration
+	MaxLatency     time.Duration
+	QueueDepth     int
+	MaxQueueDepth  int
+	ActiveWorkers  int
+	BusyWorkers    int
 }
 
 // NewWorkerPool creates a new WorkerPool with advanced features.
 func NewWorkerPool(ctx context.Context, minWorkers, maxWorkers int, scalingInterval time.Duration) *WorkerPool {
 	ctx, cancel := context.WithCancel(ctx)
-	return &WorkerPool{
+	wp := &WorkerPool{
 		tasks:           make(priorityQueue, 0),
 		minWorkers:      minWorkers,
 		maxWorkers:      maxWorkers,
-		activeWorkers:   minWorkers,
 		scalingInterval: scalingInterval,
 		ctx:             ctx,
 		cancelFunc:      cancel,
 		metrics:         &Metrics{},
-		shutdownCh:      make(chan struct{}),
-		workerSem:       make(chan struct{}, maxWorkers),
 		lastScaleTime:   time.Now(),
 		cooldownPeriod:  5 * time.Second,
 		taskMap:         make(map[string]struct{}), // Initialize task map
 	}
+	wp.idle = sync.NewCond(&wp.mu)
+	wp.taskCond = sync.NewCond(&wp.taskLock)
+	// Idle workers exit once the pool shuts down
+	context.AfterFunc(ctx, func() {
+		wp.taskLock.Lock()
+		wp.taskCond.Broadcast()
+		wp.taskLock.Unlock()
+	})
+	return wp
 }
 
-// AddTask adds a task to the worker pool's priority queue.
-func (wp *WorkerPool) AddTask(task *Task) {
-	taskID := fmt.Sprintf("%p", task.Job) // Example task ID based on the function pointer. Customize as needed.
+// AddTask adds a task to the worker pool's priority queue. It fails for a
+// task with the ID of one still queued or running, and once the pool is shut
+// down.
+func (wp *WorkerPool) AddTask(task *Task) error {
+	task.Future()
+
+	if task.Ctx == nil {
+		task.Ctx = context.Background()
+	}
+
+	// Shutting the pool down cancels the task through CancelFunc
+	if task.CancelFunc == nil {
+		task.Ctx, task.CancelFunc = context.WithCancel(task.Ctx)
+	}
+
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
 
 	wp.taskLock.Lock()
 	defer wp.taskLock.Unlock()
 
+	if err := wp.ctx.Err(); err != nil {
+		return err
+	}
+
+	if task.ID == "" {
+		wp.nextID++
+		task.ID = fmt.Sprintf("task-%d", wp.nextID)
+	}
+
 	// Check for duplicates
-	if _, exists := wp.taskMap[taskID]; exists {
-		fmt.Printf("Duplicate task detected. Task not added.\n")
-		return
+	if _, exists := wp.taskMap[task.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateTask, task.ID)
 	}
 
 	// Add task to the map to track it
-	wp.taskMap[taskID] = struct{}{}
+	wp.taskMap[task.ID] = struct{}{}
 
-	heap.Push(&wp.tasks, task)
+	wp.mu.Lock()
+	wp.pending++
+	wp.mu.Unlock()
+
+	wp.push(task)
 	wp.tasksAdded++
-	fmt.Printf("Task added. Total tasks: %d\n", wp.tasksAdded)
+	fmt.Printf("Task %s added. Total tasks: %d\n", task.ID, wp.tasksAdded)
+
+	return nil
 }
 
 // CancelTask cancels a specific task.
@@ -143,82 +315,215 @@ func (wp *WorkerPool) CancelTask(task *Task) {
 	task.CancelFunc()
 }
 
-// worker is a goroutine that processes tasks.
+// push queues task and wakes an idle worker for it. taskLock must be held.
+func (wp *WorkerPool) push(task *Task) {
+	task.queuedAt = time.Now()
+	heap.Push(&wp.tasks, task)
+	wp.metrics.updateQueueDepth(wp.tasks.Len())
+	wp.taskCond.Signal()
+}
+
+// worker is a goroutine that processes tasks until the pool shuts down or
+// retires it.
 func (wp *WorkerPool) worker(id int) {
 	defer wp.workerWg.Done()
 	for {
-		select {
-		case <-wp.ctx.Done():
-			return
-		case <-wp.shutdownCh:
+		task, ok := wp.nextTask()
+		if !ok {
 			return
-		default:
-			wp.processNextTask(id)
 		}
+
+		wp.processTask(id, task)
+
+		wp.taskLock.Lock()
+		wp.busy--
+		wp.taskLock.Unlock()
 	}
 }
 
-// processNextTask handles the next task in the queue.
-func (wp *WorkerPool) processNextTask(id int) {
+// nextTask waits for the task with the highest priority. It returns false
+// once the pool is shut down, or when the queue is empty and the worker is to
+// retire.
+func (wp *WorkerPool) nextTask() (*Task, bool) {
 	wp.taskLock.Lock()
-	if wp.tasks.Len() == 0 {
-		wp.taskLock.Unlock()
-		time.Sleep(100 * time.Millisecond)
-		return
+	defer wp.taskLock.Unlock()
+
+	for wp.tasks.Len() == 0 || wp.ctx.Err() != nil {
+		if wp.ctx.Err() != nil {
+			return nil, false
+		}
+		if wp.retiring > 0 {
+			wp.retiring--
+			wp.activeWorkers--
+			return nil, false
+		}
+		wp.taskCond.Wait()
 	}
-	task := heap.Pop(&wp.tasks).(*Task)
-	taskID := fmt.Sprintf("%p", task.Job) // Use the same task ID logic
-	wp.taskLock.Unlock()
 
+	wp.busy++
+	return heap.Pop(&wp.tasks).(*Task), true
+}
+
+// processTask makes one attempt of task.
+func (wp *WorkerPool) processTask(id int, task *Task) {
 	startTime := time.Now()
-	wp.metrics.updateWaitTime(startTime.Sub(task.CreatedAt))
+	wp.metrics.updateWaitTime(startTime.Sub(task.queuedAt))
 
-	select {
-	case <-task.Ctx.Done():
-		if task.Ctx.Err() == context.DeadlineExceeded {
-			fmt.Printf("Worker %d: Task timed out\n", id)
-		} else {
-			fmt.Printf("Worker %d: Task canceled\n", id)
-		}
-		wp.metrics.incrementTasksFailed()
-	default:
-		if err := task.Job(task.Ctx); err != nil {
-			fmt.Printf("Worker %d: Error: %v\n", id, err)
-			wp.metrics.incrementTasksFailed()
-		} else {
-			wp.metrics.incrementTasksCompleted()
-		}
+	// Shutting the pool down cancels the task in flight
+	stop := context.AfterFunc(wp.ctx, task.CancelFunc)
+	defer stop()
+
+	if err := task.Ctx.Err(); err != nil {
+		fmt.Printf("Worker %d: Task %s canceled\n", id, task.ID)
+		wp.finish(task, err)
+		return
 	}
 
+	task.attempts++
+	err := task.run()
+
 	wp.metrics.updateProcessingTime(time.Since(startTime))
 
+	if err != nil && wp.retryable(task, err) {
+		wait := task.Retry.backoff(task.attempts)
+		fmt.Printf("Worker %d: Task %s failed: %v, retrying in %v\n", id, task.ID, err, wait)
+		wp.retry(task, wait)
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("Worker %d: Task %s failed: %v\n", id, task.ID, err)
+	}
+
+	wp.finish(task, err)
+}
+
+// retryable reports whether task gets another attempt after failing with err.
+func (wp *WorkerPool) retryable(task *Task, err error) bool {
+	if task.attempts >= task.Retry.MaxAttempts || task.Ctx.Err() != nil || wp.ctx.Err() != nil {
+		return false
+	}
+	return task.Retry.Retryable == nil || task.Retry.Retryable(err)
+}
+
+// backoff is the pause before the retry that follows attempt.
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	wait := r.Backoff << (attempt - 1)
+	if wait < 0 || (r.MaxBackoff > 0 && wait > r.MaxBackoff) {
+		return r.MaxBackoff
+	}
+	return wait
+}
+
+// retry queues task again once wait is over.
+func (wp *WorkerPool) retry(task *Task, wait time.Duration) {
+	wp.mu.Lock()
+	wp.report.Retried++
+	wp.mu.Unlock()
+	wp.metrics.incrementTasksRetried()
+
+	go func() {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-task.Ctx.Done():
+			wp.finish(task, task.Ctx.Err())
+			return
+		case <-wp.ctx.Done():
+			wp.finish(task, wp.ctx.Err())
+			return
+		}
+
+		wp.taskLock.Lock()
+		if err := wp.ctx.Err(); err != nil {
+			wp.taskLock.Unlock()
+			wp.finish(task, err)
+			return
+		}
+		wp.push(task)
+		wp.taskLock.Unlock()
+	}()
+}
+
+// finish records the result of task and hands it to its future.
+func (wp *WorkerPool) finish(task *Task, err error) {
+	result := Result{TaskID: task.ID, Value: task.value, Err: err, Attempts: task.attempts, Latency: time.Since(task.CreatedAt)}
+
+	switch {
+	case err == nil:
+		wp.metrics.incrementTasksCompleted()
+	default:
+		wp.metrics.incrementTasksFailed()
+	}
+	wp.metrics.updateLatency(result.Latency)
+
 	// Critical section to update and print task completion safely
 	wp.mu.Lock()
+	switch {
+	case err == nil:
+		wp.report.Completed++
+	case task.Ctx.Err() != nil || wp.ctx.Err() != nil:
+		wp.report.Cancelled++
+	default:
+		wp.report.Failed++
+		wp.report.DeadLetters = append(wp.report.DeadLetters, result)
+	}
 	wp.tasksCompleted++
 	completed := wp.tasksCompleted
-	added := wp.tasksAdded
+	wp.pending--
+	if wp.pending == 0 {
+		wp.idle.Broadcast()
+	}
+	wp.mu.Unlock()
 
 	// Clean up task from taskMap
 	wp.taskLock.Lock()
-	delete(wp.taskMap, taskID)
+	delete(wp.taskMap, task.ID)
+	added := wp.tasksAdded
 	wp.taskLock.Unlock()
 
-	wp.mu.Unlock()
+	task.future.result = result
+	close(task.future.done)
+
+	fmt.Printf("Task %s completed. Completed tasks: %d/%d\n", task.ID, completed, added)
+}
+
+// drain finishes the tasks still queued with err.
+func (wp *WorkerPool) drain(err error) {
+	wp.taskLock.Lock()
+	tasks := wp.tasks
+	wp.tasks = priorityQueue{}
+	wp.taskLock.Unlock()
 
-	fmt.Printf("Task completed. Completed tasks: %d/%d\n", completed, added)
+	for _, task := range tasks {
+		wp.finish(task, err)
+	}
 }
 
 // Run starts the initial workers and the auto-scaling process.
 func (wp *WorkerPool) Run() {
+	wp.taskLock.Lock()
 	for i := 0; i < wp.minWorkers; i++ {
-		wp.workerWg.Add(1)
-		wp.workerSem <- struct{}{} // Add this line
-		go wp.worker(i)
+		wp.startWorker()
 	}
+	wp.taskLock.Unlock()
 
 	go wp.autoScale()
 }
 
+// startWorker starts one more worker. taskLock must be held.
+func (wp *WorkerPool) startWorker() {
+	if wp.ctx.Err() != nil {
+		return
+	}
+	wp.workerWg.Add(1)
+	wp.activeWorkers++
+	wp.nextWorker++
+	go wp.worker(wp.nextWorker)
+}
+
 // autoScale automatically scales the number of workers based on the task queue load.
 func (wp *WorkerPool) autoScale() {
 	ticker := time.NewTicker(wp.scalingInterval)
@@ -229,92 +534,126 @@ func (wp *WorkerPool) autoScale() {
 		case <-wp.ctx.Done():
 			return
 		case <-ticker.C:
-			wp.mu.Lock()
+			wp.taskLock.Lock()
 			taskQueueSize := wp.tasks.Len()
+			workers := wp.activeWorkers - wp.retiring
 			now := time.Now()
 			if now.Sub(wp.lastScaleTime) > wp.cooldownPeriod {
-				if taskQueueSize > wp.activeWorkers && wp.activeWorkers < wp.maxWorkers {
+				if taskQueueSize > workers && workers < wp.maxWorkers {
 					wp.scaleUp(taskQueueSize)
 					wp.lastScaleTime = now
-				} else if taskQueueSize < wp.activeWorkers/2 && wp.activeWorkers > wp.minWorkers {
+				} else if taskQueueSize < workers/2 && workers > wp.minWorkers {
 					wp.scaleDown()
 					wp.lastScaleTime = now
 				}
 			}
-			wp.mu.Unlock()
+			wp.taskLock.Unlock()
 		}
 	}
 }
 
-// scaleUp increases the number of workers.
+// scaleUp increases the number of workers, keeping those about to retire
+// first. taskLock must be held.
 func (wp *WorkerPool) scaleUp(taskQueueSize int) {
-	newWorkers := min(taskQueueSize-wp.activeWorkers, wp.maxWorkers-wp.activeWorkers)
-	for i := 0; i < newWorkers; i++ {
-		wp.workerSem <- struct{}{}
-		wp.workerWg.Add(1) // Add this line
-		go wp.worker(wp.activeWorkers + i)
+	workers := wp.activeWorkers - wp.retiring
+	newWorkers := min(taskQueueSize-workers, wp.maxWorkers-workers)
+	kept := min(newWorkers, wp.retiring)
+	wp.retiring -= kept
+	for i := kept; i < newWorkers; i++ {
+		wp.startWorker()
 	}
-	wp.activeWorkers += newWorkers
-	fmt.Printf("Scaled up to %d workers\n", wp.activeWorkers)
+	fmt.Printf("Scaled up to %d workers\n", wp.activeWorkers-wp.retiring)
 }
 
-// scaleDown reduces the number of workers.
+// scaleDown retires workers as they become idle. taskLock must be held.
 func (wp *WorkerPool) scaleDown() {
-	workersToRemove := (wp.activeWorkers - wp.minWorkers) / 2 // Remove half of the excess workers
+	workers := wp.activeWorkers - wp.retiring
+	workersToRemove := (workers - wp.minWorkers) / 2 // Remove half of the excess workers
 	if workersToRemove == 0 {
 		workersToRemove = 1 // Remove at least one worker
 	}
-	for i := 0; i < workersToRemove; i++ {
-		<-wp.workerSem
-	}
-	wp.activeWorkers -= workersToRemove
-	fmt.Printf("Scaled down to %d workers\n", wp.activeWorkers)
+	wp.retiring += workersToRemove
+	wp.taskCond.Broadcast()
+	fmt.Printf("Scaled down to %d workers\n", workers-workersToRemove)
 }
 
-// Wait waits for all tasks to complete.
-// Wait waits for all tasks to complete.
-func (wp *WorkerPool) Wait() {
-	fmt.Println("Waiting for all tasks to complete...")
+// Wait waits for all tasks to complete, fail or be cancelled, and reports on
+// every task run by the pool so far. Once the pool's context is done, the
+// tasks still queued are cancelled instead of run.
+func (wp *WorkerPool) Wait() Report {
+	stop := context.AfterFunc(wp.ctx, func() {
+		wp.mu.Lock()
+		wp.idle.Broadcast()
+		wp.mu.Unlock()
+	})
+	defer stop()
 
-	// Wait for tasks to be completed
-	for {
-		wp.mu.RLock()
-		completed := wp.tasksCompleted
-		added := wp.tasksAdded
-		wp.mu.RUnlock()
+	wp.mu.Lock()
+	for wp.pending > 0 && wp.ctx.Err() == nil {
+		wp.idle.Wait()
+	}
+	wp.mu.Unlock()
 
-		if completed >= added {
-			break
-		}
+	if err := wp.ctx.Err(); err != nil {
+		wp.workerWg.Wait()
+		wp.drain(err)
 
-		fmt.Printf("Still waiting... Completed tasks: %d/%d\n", completed, added)
-		time.Sleep(500 * time.Millisecond)
+		// Tasks waiting to be retried finish on their own
+		wp.mu.Lock()
+		for wp.pending > 0 {
+			wp.idle.Wait()
+		}
+		wp.mu.Unlock()
 	}
 
-	fmt.Println("All tasks completed.")
+	return wp.Report()
+}
+
+// Report sums up the tasks run by the pool so far.
+func (wp *WorkerPool) Report() Report {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	report := wp.report
+	report.DeadLetters = append([]Result(nil), wp.report.DeadLetters...)
+	return report
+}
+
+// DeadLetters returns the tasks that failed on their last attempt.
+func (wp *WorkerPool) DeadLetters() []Result {
+	return wp.Report().DeadLetters
 }
 
 // Shutdown gracefully shuts down the worker pool.
 func (wp *WorkerPool) Shutdown() {
 	fmt.Println("Shutting down worker pool...")
 	wp.cancelFunc()
-	close(wp.shutdownCh)
 	wp.workerWg.Wait()
-	wp.taskLock.Lock()
-	wp.tasks = priorityQueue{}
-	wp.taskLock.Unlock()
+	wp.drain(context.Canceled)
 	fmt.Println("Worker pool shut down.")
 }
 
 // GetMetrics returns a snapshot of the current metrics of the worker pool.
 func (wp *WorkerPool) GetMetrics() MetricsSnapshot {
+	wp.taskLock.Lock()
+	queueDepth := wp.tasks.Len()
+	activeWorkers := wp.activeWorkers - wp.retiring
+	busyWorkers := wp.busy
+	wp.taskLock.Unlock()
+
 	wp.metrics.mu.Lock()
 	defer wp.metrics.mu.Unlock()
 	return MetricsSnapshot{
 		TasksCompleted:        wp.metrics.TasksCompleted,
 		TasksFailed:           wp.metrics.TasksFailed,
+		TasksRetried:          wp.metrics.TasksRetried,
 		AverageWaitTime:       wp.metrics.AverageWaitTime,
 		AverageProcessingTime: wp.metrics.AverageProcessingTime,
+		AverageLatency:        wp.metrics.AverageLatency,
+		MaxLatency:            wp.metrics.MaxLatency,
+		QueueDepth:            queueDepth,
+		MaxQueueDepth:         wp.metrics.MaxQueueDepth,
+		ActiveWorkers:         activeWorkers,
+		BusyWorkers:           busyWorkers,
 	}
 }
 
@@ -325,6 +664,12 @@ func (m *Metrics) incrementTasksCompleted() {
 	m.TasksCompleted++
 }
 
+func (m *Metrics) incrementTasksRetried() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TasksRetried++
+}
+
 func (m *Metrics) incrementTasksFailed() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -334,13 +679,33 @@ func (m *Metrics) incrementTasksFailed() {
 func (m *Metrics) updateWaitTime(duration time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.AverageWaitTime = (m.AverageWaitTime*time.Duration(m.TasksCompleted) + duration) / time.Duration(m.TasksCompleted+1)
+	m.waits++
+	m.AverageWaitTime += (duration - m.AverageWaitTime) / time.Duration(m.waits)
 }
 
 func (m *Metrics) updateProcessingTime(duration time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.AverageProcessingTime = (m.AverageProcessingTime*time.Duration(m.TasksCompleted) + duration) / time.Duration(m.TasksCompleted+1)
+	m.attempts++
+	m.AverageProcessingTime += (duration - m.AverageProcessingTime) / time.Duration(m.attempts)
+}
+
+func (m *Metrics) updateLatency(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished++
+	m.AverageLatency += (duration - m.AverageLatency) / time.Duration(m.finished)
+	if duration > m.MaxLatency {
+		m.MaxLatency = duration
+	}
+}
+
+func (m *Metrics) updateQueueDepth(depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if depth > m.MaxQueueDepth {
+		m.MaxQueueDepth = depth
+	}
 }
 
 // Helper function to calculate the minimum of two integers.
@@ -363,16 +728,20 @@ func Test() {
 	for i := 0; i < 20; i++ {
 		taskNum := i
 		priority := 1 //rand.Intn(5) // Random priority between 0 and 4
-		task := NewTask(func(ctx context.Context) error {
+		task := NewValueTask(fmt.Sprintf("task-%d", taskNum), func(ctx context.Context) (interface{}, error) {
 			fmt.Printf("Processing task %d with priority %d\n", taskNum, priority)
 			time.Sleep(1 * time.Second)
-			return nil
-		}, priority, 5*time.Second) // 5 second timeout for each task
-		pool.AddTask(task)
+			return taskNum * taskNum, nil
+		}, priority, 5*time.Second) // 5 second timeout for each attempt
+		task.Retry = RetryPolicy{MaxAttempts: 3, Backoff: time.Second}
+		if err := pool.AddTask(task); err != nil {
+			fmt.Println("Error:", err)
+		}
 	}
 
 	// Wait for all tasks to complete.
-	pool.Wait()
+	report := pool.Wait()
+	fmt.Printf("Report: %d completed, %d failed, %d cancelled, %d retried\n", report.Completed, report.Failed, report.Cancelled, report.Retried)
 
 	// Add a small delay to ensure all goroutines have completed
 	time.Sleep(1 * time.Second)
@@ -381,8 +750,11 @@ func Test() {
 	metrics := pool.GetMetrics()
 	fmt.Printf("Tasks completed: %d\n", metrics.TasksCompleted)
 	fmt.Printf("Tasks failed: %d\n", metrics.TasksFailed)
+	fmt.Printf("Tasks retried: %d\n", metrics.TasksRetried)
 	fmt.Printf("Average wait time: %v\n", metrics.AverageWaitTime)
 	fmt.Printf("Average processing time: %v\n", metrics.AverageProcessingTime)
+	fmt.Printf("Average latency: %v\n", metrics.AverageLatency)
+	fmt.Printf("Max queue depth: %d\n", metrics.MaxQueueDepth)
 
 	// Shut down the pool after all tasks are done.
 	pool.Shutdown()
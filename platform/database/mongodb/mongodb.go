@@ -73,9 +73,9 @@ func GetCollection(collectionName string) *mongo.Collection {
 	return db.Collection(collectionName)
 }
 
-func isReplicaSet(client *mongo.Client) (bool, error) {
+func isReplicaSet(ctx context.Context, client *mongo.Client) (bool, error) {
 	var result bson.M
-	err := client.Database("admin").RunCommand(context.Background(), bson.D{{Key: "isMaster", Value: 1}}).Decode(&result)
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&result)
 	if err != nil {
 		return false, fmt.Errorf("failed to run isMaster command: %v", err)
 	}
@@ -88,8 +88,8 @@ func isReplicaSet(client *mongo.Client) (bool, error) {
 }
 
 // withTransaction handles operations with or without transactions based on the replica set status
-func withTransaction(txnFn func(sessCtx mongo.SessionContext) (interface{}, error)) (interface{}, error) {
-	isReplicaSet, err := isReplicaSet(client)
+func withTransaction(ctx context.Context, txnFn func(sessCtx mongo.SessionContext) (interface{}, error)) (interface{}, error) {
+	isReplicaSet, err := isReplicaSet(ctx, client)
 	if err != nil {
 		return nil, err
 	}
@@ -100,10 +100,10 @@ func withTransaction(txnFn func(sessCtx mongo.SessionContext) (interface{}, erro
 		if err != nil {
 			return nil, fmt.Errorf("failed to start session: %v", err)
 		}
-		defer session.EndSession(context.Background())
+		defer session.EndSession(ctx)
 
 		// Use the session and transaction
-		result, err := session.WithTransaction(context.Background(), txnFn)
+		result, err := session.WithTransaction(ctx, txnFn)
 		if err != nil {
 			return nil, fmt.Errorf("transaction failed: %v", err)
 		}
@@ -116,13 +116,12 @@ func withTransaction(txnFn func(sessCtx mongo.SessionContext) (interface{}, erro
 	log.Println("[MONGO_DB] not a replica set, running operation without transaction")
 
 	// Create a fake session context by wrapping a regular context
-	ctx := mongo.NewSessionContext(context.Background(), nil)
-	return txnFn(ctx)
+	return txnFn(mongo.NewSessionContext(ctx, nil))
 }
 
 // FindAndUpdateWithRollback finds a document, updates it, and rolls back if there's an error
-func FindAndUpdateWithRollback(collectionName string, filter interface{}, update interface{}) (interface{}, error) {
-	return withTransaction(func(sessCtx mongo.SessionContext) (interface{}, error) {
+func FindAndUpdateWithRollback(ctx context.Context, collectionName string, filter interface{}, update interface{}) (interface{}, error) {
+	return withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		coll := GetCollection(collectionName)
 
 		var updatedDoc bson.M
@@ -141,8 +140,8 @@ func FindAndUpdateWithRollback(collectionName string, filter interface{}, update
 }
 
 // InsertDocumentWithRollback inserts a new document with rollback capability
-func InsertDocumentWithRollback(collectionName string, document interface{}) (interface{}, error) {
-	return withTransaction(func(sessCtx mongo.SessionContext) (interface{}, error) {
+func InsertDocumentWithRollback(ctx context.Context, collectionName string, document interface{}) (interface{}, error) {
+	return withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		coll := GetCollection(collectionName)
 
 		result, err := coll.InsertOne(sessCtx, document)
@@ -156,8 +155,8 @@ func InsertDocumentWithRollback(collectionName string, document interface{}) (in
 }
 
 // DeleteDocumentWithRollback deletes a document with rollback capability
-func DeleteDocumentWithRollback(collectionName string, filter interface{}) (int64, error) {
-	result, err := withTransaction(func(sessCtx mongo.SessionContext) (interface{}, error) {
+func DeleteDocumentWithRollback(ctx context.Context, collectionName string, filter interface{}) (int64, error) {
+	result, err := withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		coll := GetCollection(collectionName)
 
 		result, err := coll.DeleteOne(sessCtx, filter)
@@ -176,9 +175,9 @@ func DeleteDocumentWithRollback(collectionName string, filter interface{}) (int6
 }
 
 // FindDocuments finds documents in the specified collection based on a filter
-func FindDocuments(collectionName string, filter interface{}, limit int64, sort interface{}) ([]bson.M, error) {
+func FindDocuments(ctx context.Context, collectionName string, filter interface{}, limit int64, sort interface{}) ([]bson.M, error) {
 	coll := GetCollection(collectionName)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	// Handle nil filter
@@ -212,9 +211,9 @@ func FindDocuments(collectionName string, filter interface{}, limit int64, sort
 }
 
 // FindOne finds a single document in the specified collection based on a filter
-func FindOne(collectionName string, filter interface{}) (bson.M, error) {
+func FindOne(ctx context.Context, collectionName string, filter interface{}) (bson.M, error) {
 	coll := GetCollection(collectionName)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	var result bson.M
@@ -229,9 +228,65 @@ func FindOne(collectionName string, filter interface{}) (bson.M, error) {
 	return result, nil
 }
 
+// FindOneAndModify updates the first document matching filter in sort order
+// and returns it as updated. Unlike FindAndUpdateWithRollback it never inserts
+// a document, and returns mongo.ErrNoDocuments when none matches.
+func FindOneAndModify(ctx context.Context, collectionName string, filter interface{}, update interface{}, sort interface{}) (bson.M, error) {
+	coll := GetCollection(collectionName)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if sort != nil {
+		opts.SetSort(sort)
+	}
+
+	var result bson.M
+	err := coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update document: %v", err)
+	}
+
+	return result, nil
+}
+
+// UpdateOne updates the first document matching filter without inserting one,
+// and returns how many documents matched.
+func UpdateOne(ctx context.Context, collectionName string, filter interface{}, update interface{}) (int64, error) {
+	coll := GetCollection(collectionName)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update document: %v", err)
+	}
+
+	return result.MatchedCount, nil
+}
+
+// UpsertOne updates the first document matching filter, or inserts one when
+// none does. Unlike FindAndUpdateWithRollback it runs outside a transaction
+// and does not read the document back.
+func UpsertOne(ctx context.Context, collectionName string, filter interface{}, update interface{}) error {
+	coll := GetCollection(collectionName)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to upsert document: %v", err)
+	}
+
+	return nil
+}
+
 // BulkWriteWithRollback performs multiple write operations with rollback capability
-func BulkWriteWithRollback(collectionName string, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
-	result, err := withTransaction(func(sessCtx mongo.SessionContext) (interface{}, error) {
+func BulkWriteWithRollback(ctx context.Context, collectionName string, operations []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	result, err := withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		coll := GetCollection(collectionName)
 
 		opts := options.BulkWrite().SetOrdered(false)
@@ -251,8 +306,8 @@ func BulkWriteWithRollback(collectionName string, operations []mongo.WriteModel)
 }
 
 // AggregateWithRollback performs an aggregation pipeline with rollback capability
-func AggregateWithRollback(collectionName string, pipeline interface{}) ([]bson.M, error) {
-	result, err := withTransaction(func(sessCtx mongo.SessionContext) (interface{}, error) {
+func AggregateWithRollback(ctx context.Context, collectionName string, pipeline interface{}) ([]bson.M, error) {
+	result, err := withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		coll := GetCollection(collectionName)
 
 		cursor, err := coll.Aggregate(sessCtx, pipeline)
@@ -277,8 +332,8 @@ func AggregateWithRollback(collectionName string, pipeline interface{}) ([]bson.
 }
 
 // CreateIndexWithRollback creates an index with rollback capability
-func CreateIndexWithRollback(collectionName string, keys bson.D, options *options.IndexOptions) (string, error) {
-	result, err := withTransaction(func(sessCtx mongo.SessionContext) (interface{}, error) {
+func CreateIndexWithRollback(ctx context.Context, collectionName string, keys bson.D, options *options.IndexOptions) (string, error) {
+	result, err := withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		coll := GetCollection(collectionName)
 
 		indexName, err := coll.Indexes().CreateOne(sessCtx, mongo.IndexModel{Keys: keys, Options: options})
@@ -297,8 +352,8 @@ func CreateIndexWithRollback(collectionName string, keys bson.D, options *option
 }
 
 // DropIndexWithRollback drops an index with rollback capability
-func DropIndexWithRollback(collectionName string, indexName string) error {
-	_, err := withTransaction(func(sessCtx mongo.SessionContext) (interface{}, error) {
+func DropIndexWithRollback(ctx context.Context, collectionName string, indexName string) error {
+	_, err := withTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
 		coll := GetCollection(collectionName)
 
 		_, err := coll.Indexes().DropOne(sessCtx, indexName)
@@ -0,0 +1,403 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"pnl-scan-tool/package/utils"
+	"pnl-scan-tool/platform/database/mongodb"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Statuses of a message.
+const (
+	StatusPending = "pending"
+	StatusLeased  = "leased"
+	StatusDone    = "done"
+	StatusDead    = "dead"
+)
+
+// ErrEmpty is returned by Lease when no message is available.
+var ErrEmpty = errors.New("queue empty")
+
+// ErrNotFound is returned by Get for an unknown message.
+var ErrNotFound = errors.New("message not found")
+
+// ErrLeaseLost is returned for a message whose lease expired and was taken by
+// another consumer, or that was settled already.
+var ErrLeaseLost = errors.New("lease lost")
+
+// Message is a unit of work stored in the queue.
+type Message struct {
+	ID       string   `json:"id" bson:"_id"`
+	Queue    string   `json:"queue" bson:"queue"`
+	Payload  bson.Raw `json:"-" bson:"payload"`
+	Status   string   `json:"status" bson:"status"`
+	Priority int      `json:"priority" bson:"priority"`
+	Attempts int      `json:"attempts" bson:"attempts"`
+	// MaxAttempts is how many leases the message gets before it is dead.
+	MaxAttempts int `json:"max-attempts" bson:"maxattempts"`
+	// Owner is the consumer holding the lease, until LeasedUntil.
+	Owner       string `json:"owner,omitempty" bson:"owner,omitempty"`
+	LeasedUntil int64  `json:"leased-until,omitempty" bson:"leaseduntil,omitempty"`
+	// AvailableAt is when the message can be leased, later than CreatedAt
+	// after a nack.
+	AvailableAt int64  `json:"available-at" bson:"availableat"`
+	LastError   string `json:"last-error,omitempty" bson:"lasterror,omitempty"`
+	CreatedAt   int64  `json:"created-at" bson:"createdat"`
+	UpdatedAt   int64  `json:"updated-at" bson:"updatedat"`
+}
+
+// Decode unmarshals the payload of m into v.
+func (m *Message) Decode(v interface{}) error {
+	return bson.Unmarshal(m.Payload, v)
+}
+
+// Queue is a named queue of messages in a MongoDB collection. Every process
+// using the same database shares it: a message is leased to one consumer at a
+// time, and handed out again when its lease expires unacked, so that the work
+// of a crashed or restarted process is picked up by the next one.
+type Queue struct {
+	Collection string
+	Name       string
+	// Visibility is how long a leased message stays hidden from the other
+	// consumers. Consume extends it while the message is being handled.
+	Visibility time.Duration
+	// MaxAttempts is how many times a message is leased before it is dead.
+	MaxAttempts int
+	// Backoff is the delay before a nacked message is available again,
+	// doubled on every attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// PollInterval is how long Consume waits when the queue is empty.
+	PollInterval time.Duration
+	// Owner identifies this process in the leases it takes.
+	Owner string
+}
+
+// New returns the queue name stored in collection, with default timings.
+func New(collection string, name string) *Queue {
+	hostname, _ := os.Hostname()
+
+	return &Queue{
+		Collection:   collection,
+		Name:         name,
+		Visibility:   5 * time.Minute,
+		MaxAttempts:  3,
+		Backoff:      30 * time.Second,
+		MaxBackoff:   30 * time.Minute,
+		PollInterval: 2 * time.Second,
+		Owner:        hostname + "-" + primitive.NewObjectID().Hex(),
+	}
+}
+
+// EnsureIndexes creates the index Lease looks messages up with.
+func (q *Queue) EnsureIndexes(ctx context.Context) error {
+	keys := bson.D{{Key: "queue", Value: 1}, {Key: "status", Value: 1}, {Key: "availableat", Value: 1}}
+
+	_, err := mongodb.CreateIndexWithRollback(ctx, q.Collection, keys, nil)
+
+	return err
+}
+
+// Enqueue stores a pending message carrying payload. Messages of higher
+// priority are leased first.
+func (q *Queue) Enqueue(ctx context.Context, payload interface{}, priority int) (*Message, error) {
+	raw, err := bson.Marshal(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+
+	m := &Message{
+		ID:          primitive.NewObjectID().Hex(),
+		Queue:       q.Name,
+		Payload:     raw,
+		Status:      StatusPending,
+		Priority:    priority,
+		MaxAttempts: q.MaxAttempts,
+		AvailableAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if _, err := mongodb.InsertDocumentWithRollback(ctx, q.Collection, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Get returns the message stored under id, or ErrNotFound.
+func (q *Queue) Get(ctx context.Context, id string) (*Message, error) {
+	documents, err := mongodb.FindDocuments(ctx, q.Collection, bson.M{"_id": id, "queue": q.Name}, 1, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("%w: message %s", ErrNotFound, id)
+	}
+
+	return decode(documents[0])
+}
+
+// Lease takes the available message of highest priority, or one whose lease
+// expired, and hides it from the other consumers for Visibility. It returns
+// ErrEmpty when there is none.
+func (q *Queue) Lease(ctx context.Context) (*Message, error) {
+	for {
+		now := time.Now().Unix()
+
+		filter := bson.M{
+			"queue": q.Name,
+			"$or": []bson.M{
+				{"status": StatusPending, "availableat": bson.M{"$lte": now}},
+				{"status": StatusLeased, "leaseduntil": bson.M{"$lte": now}},
+			},
+		}
+
+		update := bson.M{
+			"$set": bson.M{
+				"status":      StatusLeased,
+				"owner":       q.Owner,
+				"leaseduntil": now + int64(q.Visibility/time.Second),
+				"updatedat":   now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		}
+
+		sort := bson.D{{Key: "priority", Value: -1}, {Key: "availableat", Value: 1}}
+
+		document, err := mongodb.FindOneAndModify(ctx, q.Collection, filter, update, sort)
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrEmpty
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := decode(document)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// A message whose consumers kept dying before settling it
+		if m.Attempts > m.MaxAttempts {
+			if err := q.settle(ctx, m, bson.M{"status": StatusDead, "lasterror": "lease expired"}); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		return m, nil
+	}
+}
+
+// Extend pushes the lease of m back by Visibility.
+func (q *Queue) Extend(ctx context.Context, m *Message) error {
+	m.LeasedUntil = time.Now().Unix() + int64(q.Visibility/time.Second)
+
+	return q.settle(ctx, m, bson.M{"status": StatusLeased, "leaseduntil": m.LeasedUntil})
+}
+
+// Ack marks m as done.
+func (q *Queue) Ack(ctx context.Context, m *Message) error {
+	return q.settle(ctx, m, bson.M{"status": StatusDone, "lasterror": ""})
+}
+
+// Nack hands m back after it failed with cause. It is available again after
+// a backoff, or dead once it has used up its attempts.
+func (q *Queue) Nack(ctx context.Context, m *Message, cause error) error {
+	fields := bson.M{"status": StatusPending, "lasterror": cause.Error()}
+
+	if m.Attempts >= m.MaxAttempts {
+		fields["status"] = StatusDead
+	} else {
+		fields["availableat"] = time.Now().Add(q.backoff(m.Attempts)).Unix()
+	}
+
+	return q.settle(ctx, m, fields)
+}
+
+// Release hands m back right away, without counting the attempt, for a
+// consumer that stops before handling it.
+func (q *Queue) Release(ctx context.Context, m *Message) error {
+	m.Attempts--
+
+	return q.settle(ctx, m, bson.M{"status": StatusPending, "availableat": time.Now().Unix(), "attempts": m.Attempts})
+}
+
+// Retry makes a dead message pending again with a fresh set of attempts.
+func (q *Queue) Retry(ctx context.Context, id string) error {
+	now := time.Now().Unix()
+
+	update := bson.M{"$set": bson.M{
+		"status":      StatusPending,
+		"attempts":    0,
+		"availableat": now,
+		"updatedat":   now,
+	}}
+
+	matched, err := mongodb.UpdateOne(ctx, q.Collection, bson.M{"_id": id, "queue": q.Name, "status": StatusDead}, update)
+
+	if err != nil {
+		return err
+	}
+
+	if matched == 0 {
+		return fmt.Errorf("no dead message %s in queue %s", id, q.Name)
+	}
+
+	return nil
+}
+
+// settle updates m while this consumer still holds its lease. It still saves
+// when ctx is done, so that a consumer shutting down hands its messages back.
+func (q *Queue) settle(ctx context.Context, m *Message, fields bson.M) error {
+	fields["updatedat"] = time.Now().Unix()
+
+	filter := bson.M{"_id": m.ID, "owner": q.Owner, "status": StatusLeased}
+
+	matched, err := mongodb.UpdateOne(context.WithoutCancel(ctx), q.Collection, filter, bson.M{"$set": fields})
+
+	if err != nil {
+		return err
+	}
+
+	if matched == 0 {
+		return fmt.Errorf("%w: message %s", ErrLeaseLost, m.ID)
+	}
+
+	if status, ok := fields["status"].(string); ok {
+		m.Status = status
+	}
+
+	return nil
+}
+
+// Consume leases messages and hands them to handle on concurrency consumers
+// until ctx is done. A message is acked when handle returns nil and nacked
+// otherwise. Its lease is extended while handle runs, and handle's context is
+// cancelled if the lease is lost anyway.
+func (q *Queue) Consume(ctx context.Context, concurrency int, handle func(ctx context.Context, m *Message) error) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < max(concurrency, 1); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				m, err := q.Lease(ctx)
+
+				if err != nil {
+					if !errors.Is(err, ErrEmpty) && ctx.Err() == nil {
+						fmt.Println("Error leasing from queue", q.Name+":", err)
+					}
+
+					utils.Sleep(ctx, q.PollInterval)
+					continue
+				}
+
+				q.handle(ctx, m, handle)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// handle runs handle on m under a lease kept alive, then settles m.
+func (q *Queue) handle(ctx context.Context, m *Message, handle func(ctx context.Context, m *Message) error) {
+	handleCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	heartbeat := make(chan struct{})
+	lost := false
+
+	go func() {
+		defer close(heartbeat)
+
+		ticker := time.NewTicker(q.Visibility / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-handleCtx.Done():
+				return
+			case <-ticker.C:
+				if err := q.Extend(handleCtx, m); errors.Is(err, ErrLeaseLost) {
+					lost = true
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := handle(handleCtx, m)
+
+	cancel()
+	<-heartbeat
+
+	var settleErr error
+
+	switch {
+	case lost:
+		settleErr = fmt.Errorf("%w: message %s", ErrLeaseLost, m.ID)
+	case ctx.Err() != nil:
+		// Shutting down, the message is handled again by the next consumer
+		settleErr = q.Release(ctx, m)
+	case err == nil:
+		settleErr = q.Ack(ctx, m)
+	default:
+		fmt.Printf("Message %s of queue %s failed (attempt %d/%d): %v\n", m.ID, q.Name, m.Attempts, m.MaxAttempts, err)
+		settleErr = q.Nack(ctx, m, err)
+	}
+
+	if settleErr != nil {
+		fmt.Println("Error settling message", m.ID+":", settleErr)
+	}
+}
+
+// backoff is the delay before the attempt that follows attempt.
+func (q *Queue) backoff(attempt int) time.Duration {
+	wait := q.Backoff << max(attempt-1, 0)
+
+	if wait <= 0 || (q.MaxBackoff > 0 && wait > q.MaxBackoff) {
+		return q.MaxBackoff
+	}
+
+	return wait
+}
+
+func decode(document bson.M) (*Message, error) {
+	raw, err := bson.Marshal(document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var m Message
+
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
@@ -2,9 +2,6 @@ package handlers
 
 import (
 	"context"
-	"os"
-	"os/signal"
-	"pnl-scan-tool/package/configs"
 	"pnl-scan-tool/src/services"
 
 	"github.com/go-telegram/bot"
@@ -12,27 +9,32 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func WalletTrackerRoutes(app *fiber.App, taskManager *services.WalletTrackerTaskManager) {
-
-	var env, err = configs.LoadConfig(".")
-
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
-
-	defer cancel()
-
+// WalletTrackerRoutes registers the wallet tracker API. The trackers it adds
+// report to Telegram until ctx is done.
+func WalletTrackerRoutes(ctx context.Context, app *fiber.App, taskManager *services.WalletTrackerTaskManager, telegramToken string) error {
 	opts := []bot.Option{
 		bot.WithDefaultHandler(handler),
 	}
 
-	b, err := bot.New(env.TELEGRAM_BOT_TOKEN, opts...)
+	b, err := bot.New(telegramToken, opts...)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	app.Post("api/wallettracker/add", taskManager.AddWalletTrackerHandler(ctx, b))
 	app.Delete("api/wallettracker/delete", taskManager.CancelTaskHandler)
 	app.Get("api/wallettracker/list", taskManager.ListTasksHandler)
+	app.Get("api/wallettracker/metrics", taskManager.GetMetricsHandler)
 	app.Post("api/wallettracker/shutdown", taskManager.ShutdownHandler)
+
+	return nil
+}
+
+// PNLRoutes registers the wallet PnL and job status API.
+func PNLRoutes(app *fiber.App) {
+	app.Post("api/pnl/:chain/:wallet", services.EnqueuePNLScanHandler)
+	app.Get("api/pnl/:chain/:wallet", services.GetPNLHandler)
+	app.Get("api/jobs/:id", services.GetJobHandler)
 }
 
 func handler(ctx context.Context, b *bot.Bot, update *models.Update) {
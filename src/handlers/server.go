@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"pnl-scan-tool/src/services"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	fiberSwagger "github.com/swaggo/fiber-swagger"
+)
+
+// ShutdownTimeout is how long the server waits for the requests in flight
+// when it shuts down.
+var ShutdownTimeout = 30 * time.Second
+
+// Serve runs the HTTP API on addr, alongside the consumer of the scan queue
+// and the scheduler, until ctx is done. It then stops taking requests, lets
+// those in flight finish and hands the scans still running back to the
+// queue. The wallet tracker routes are left out without a Telegram token.
+func Serve(ctx context.Context, addr string, telegramToken string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	app := fiber.New()
+
+	// Serve Swagger UI
+	app.Get("/swagger/*", fiberSwagger.WrapHandler)
+
+	PNLRoutes(app)
+
+	taskManager := services.NewWalletTrackerTaskManager(4, 10, 2*time.Second)
+	defer taskManager.Shutdown()
+
+	if telegramToken == "" {
+		fmt.Println("TELEGRAM_BOT_TOKEN is not set, wallet tracker routes disabled")
+	} else if err := WalletTrackerRoutes(ctx, app, taskManager, telegramToken); err != nil {
+		return fmt.Errorf("failed to start wallet tracker: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		services.ConsumeScans(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		services.RunScheduler(ctx)
+	}()
+
+	listenErr := make(chan error, 1)
+
+	go func() {
+		listenErr <- app.Listen(addr)
+	}()
+
+	var err error
+
+	select {
+	case <-ctx.Done():
+	case err = <-listenErr:
+	}
+
+	fmt.Println("Shutting down server...")
+
+	if shutdownErr := app.ShutdownWithTimeout(ShutdownTimeout); shutdownErr != nil {
+		fmt.Println("Error shutting down server:", shutdownErr)
+	}
+
+	cancel()
+	wg.Wait()
+
+	fmt.Println("Server stopped.")
+
+	return err
+}
@@ -0,0 +1,144 @@
+package ethmodel
+
+import (
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+// FromPNL builds the ETH view of a chain-neutral PNL.
+func FromPNL(p *pnlmodel.PNL) *PNL {
+	view := &PNL{
+		WalletAddress: p.WalletAddress,
+		SummaryReview: fromSummaryReview(p.SummaryReview),
+		Incomplete:    p.Incomplete,
+	}
+
+	for _, history := range p.TradeHistory {
+		view.TradeHistory = append(view.TradeHistory, fromTradeHistory(history))
+	}
+
+	for _, xpnl := range p.XPNLs {
+		view.XPNLs = append(view.XPNLs, fromXPNL(xpnl))
+	}
+
+	for _, lost := range p.LostXPNLs {
+		view.LostXPNLs = append(view.LostXPNLs, fromLostXPNL(lost))
+	}
+
+	return view
+}
+
+func fromTradeHistory(h pnlmodel.TradeHistory) TradeHistory {
+	history := TradeHistory{
+		TokenAddress: h.TokenAddress,
+		TokenSymbol:  h.TokenSymbol,
+		StartTime:    h.StartTime,
+		EndTime:      h.EndTime,
+		Truncated:    h.Truncated,
+	}
+
+	for _, e := range h.EventTrades {
+		history.EventTrades = append(history.EventTrades, EventTrade{
+			// Type used to hold the side of the trade, which EventType
+			// carries now
+			Type:         e.EventType,
+			EventType:    e.EventType,
+			PriceETH:     e.Price,
+			ETHAmount:    e.QuoteAmount,
+			TokensAmount: e.TokensAmount,
+			Timestamp:    e.Timestamp,
+			DateTime:     e.DateTime,
+		})
+	}
+
+	return history
+}
+
+func fromXPNL(x pnlmodel.XPNL) XPNL {
+	return XPNL{
+		TokenAddress:           x.TokenAddress,
+		TokenSymbol:            x.TokenSymbol,
+		CountBuy:               x.CountBuy,
+		CountSell:              x.CountSell,
+		CountSellActual:        x.CountSellActual,
+		TotalTokenBuy:          x.TotalTokenBuy,
+		TotalTokenSell:         x.TotalTokenSell,
+		TotalTokenSellActual:   x.TotalTokenSellActual,
+		TotalETHBuy:            x.TotalQuoteBuy,
+		TotalETHSell:           x.TotalQuoteSell,
+		TotalETHSellActual:     x.TotalQuoteSellActual,
+		TokenHoldAmount:        x.TokenHoldAmount,
+		TokenHoldETHAmount:     x.TokenHoldQuoteAmount,
+		ProfitETH:              x.ProfitQuote,
+		ProfitETHActual:        x.ProfitQuoteActual,
+		TotalFees:              x.TotalFees,
+		ProfitETHNet:           x.ProfitQuoteNet,
+		XPNL:                   x.XPNL,
+		XPNLRate:               x.XPNLRate,
+		PriceETHFirstBuy:       x.PriceQuoteFirstBuy,
+		PriceETHBestSell:       x.PriceQuoteBestSell,
+		XPNLTrade:              x.XPNLTrade,
+		XPNLRateTrade:          x.XPNLRateTrade,
+		RealizedProfitUSD:      x.RealizedProfitUSD,
+		UnrealizedProfitUSD:    x.UnrealizedProfitUSD,
+		ProfitUSD:              x.ProfitUSD,
+		ROIUSD:                 x.ROIUSD,
+		UnexplainedTokenAmount: x.UnexplainedTokenAmount,
+		UnexplainedRate:        x.UnexplainedRate,
+		StartTime:              x.StartTime,
+		EndTime:                x.EndTime,
+	}
+}
+
+func fromLostXPNL(x pnlmodel.LostXPNL) LostXPNL {
+	return LostXPNL{
+		TokenAddress:           x.TokenAddress,
+		TokenSymbol:            x.TokenSymbol,
+		CountBuy:               x.CountBuy,
+		CountSell:              x.CountSell,
+		CountSellActual:        x.CountSellActual,
+		TotalTokenBuy:          x.TotalTokenBuy,
+		TotalTokenSell:         x.TotalTokenSell,
+		TotalTokenSellActual:   x.TotalTokenSellActual,
+		TotalETHBuy:            x.TotalQuoteBuy,
+		TotalETHSell:           x.TotalQuoteSell,
+		TotalETHSellActual:     x.TotalQuoteSellActual,
+		TokenHoldAmount:        x.TokenHoldAmount,
+		TokenHoldETHAmount:     x.TokenHoldQuoteAmount,
+		ProfitETH:              x.ProfitQuote,
+		ProfitETHActual:        x.ProfitQuoteActual,
+		TotalFees:              x.TotalFees,
+		ProfitETHNet:           x.ProfitQuoteNet,
+		LostXPNL:               x.XPNL,
+		LostXPNLRate:           x.XPNLRate,
+		PriceETHFirstBuy:       x.PriceQuoteFirstBuy,
+		PriceETHBestSell:       x.PriceQuoteBestSell,
+		LostXPNLTrade:          x.XPNLTrade,
+		LostXPNLRateTrade:      x.XPNLRateTrade,
+		RealizedProfitUSD:      x.RealizedProfitUSD,
+		UnrealizedProfitUSD:    x.UnrealizedProfitUSD,
+		ProfitUSD:              x.ProfitUSD,
+		ROIUSD:                 x.ROIUSD,
+		UnexplainedTokenAmount: x.UnexplainedTokenAmount,
+		UnexplainedRate:        x.UnexplainedRate,
+		StartTime:              x.StartTime,
+		EndTime:                x.EndTime,
+	}
+}
+
+func fromSummaryReview(s pnlmodel.SummaryReview) SummaryReview {
+	return SummaryReview{
+		TotalETHPNLAmount:       s.TotalPNLAmount,
+		TotalETHPNLAmountActual: s.TotalPNLAmountActual,
+		TotalFees:               s.TotalFees,
+		TotalETHPNLAmountNet:    s.TotalPNLAmountNet,
+		TotalWin:                s.TotalWin,
+		TotalLost:               s.TotalLost,
+		WinRate:                 s.WinRate,
+		BigXPNL:                 s.BigXPNL,
+		RateBigXPNL:             s.RateBigXPNL,
+		TotalPNLAmountUSD:       s.TotalPNLAmountUSD,
+		ROIUSD:                  s.ROIUSD,
+		WinRateUSD:              s.WinRateUSD,
+		TotalTruncated:          s.TotalTruncated,
+	}
+}
@@ -0,0 +1,41 @@
+package jobmodel
+
+// Job kinds.
+const (
+	KindTopTraders = "toptraders"
+	KindTopHolders = "topholders"
+	KindRescan     = "rescan"
+)
+
+// Statuses of a job and of its items.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Job is a batch scan persisted item by item, so that it can be resumed from
+// where it stopped.
+type Job struct {
+	ID    string `json:"id" bson:"_id"`
+	Kind  string `json:"kind" bson:"kind"`
+	Chain string `json:"chain" bson:"chain"`
+	// Token is the token whose top traders or holders are scanned.
+	Token string `json:"token,omitempty" bson:"token,omitempty"`
+	// Mode is the rescan mode: incremental, full or backfill-usd.
+	Mode      string    `json:"mode,omitempty" bson:"mode,omitempty"`
+	Status    string    `json:"status" bson:"status"`
+	Items     []JobItem `json:"items" bson:"items"`
+	CreatedAt int64     `json:"created-at" bson:"createdat"`
+	UpdatedAt int64     `json:"updated-at" bson:"updatedat"`
+}
+
+// JobItem is one wallet of a job.
+type JobItem struct {
+	Wallet    string `json:"wallet" bson:"wallet"`
+	Status    string `json:"status" bson:"status"`
+	Attempts  int    `json:"attempts" bson:"attempts"`
+	LastError string `json:"last-error,omitempty" bson:"lasterror,omitempty"`
+}
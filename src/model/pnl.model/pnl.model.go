@@ -0,0 +1,167 @@
+package pnlmodel
+
+// Event types of a normalized EventTrade.
+const (
+	EventBuy         = "buy"
+	EventSell        = "sell"
+	EventTransferIn  = "transfer_in"
+	EventTransferOut = "transfer_out"
+	EventAirdrop     = "airdrop"
+	EventMint        = "mint"
+	EventBurn        = "burn"
+)
+
+// PNL is the chain-neutral result of a wallet scan. Every quote-denominated
+// figure is expressed in QuoteAsset (SOL, ETH, ...).
+type PNL struct {
+	WalletAddress string `json:"wallet-address" bson:"walletaddress"`
+	Chain         string `json:"chain" bson:"chain"`
+	QuoteAsset    string `json:"quote-asset" bson:"quoteasset"`
+	CostBasis     string `json:"cost-basis" bson:"costbasis"`
+	// ScanDay is the window of the scan in days, 0 for all time.
+	ScanDay      int             `json:"scan-day" bson:"scanday"`
+	TradeHistory []TradeHistory  `json:"trades" bson:"tradehistory"`
+	XPNLs        []XPNL          `json:"xpnl" bson:"xpnls"`
+	LostXPNLs    []LostXPNL      `json:"lost-xpnl" bson:"lostxpnls"`
+	Excluded     []ExcludedToken `json:"excluded-tokens" bson:"excludedtokens"`
+	// Incomplete is set when the scan was cancelled before every token was
+	// scanned.
+	Incomplete    bool          `json:"incomplete" bson:"incomplete"`
+	SummaryReview SummaryReview `json:"summary-review" bson:"summaryreview"`
+}
+
+type TradeHistory struct {
+	TokenAddress string       `json:"token-address" bson:"tokenaddress"`
+	TokenSymbol  string       `json:"token-symbol" bson:"tokensymbol"`
+	EventTrades  []EventTrade `json:"event-trades" bson:"eventtrades"`
+	LotMatches   []LotMatch   `json:"lot-matches" bson:"lotmatches"`
+
+	// Mark prices used to value the tokens still held.
+	MarkPriceQuote float64 `json:"mark-price-quote" bson:"markpricequote"`
+	MarkPriceUSD   float64 `json:"mark-price-usd" bson:"markpriceusd"`
+
+	StartTime string `json:"start-time" bson:"starttime"`
+	EndTime   string `json:"end-time" bson:"endtime"`
+
+	// Truncated is set when the source stopped before the first trade, so
+	// the figures are computed from an incomplete history.
+	Truncated bool `json:"truncated" bson:"truncated"`
+
+	// Watermark is the Unix timestamp of the newest activity read from the
+	// source. A rescan only fetches the activity from then on.
+	Watermark int64 `json:"watermark" bson:"watermark"`
+}
+
+// XPNL is the per-token result of a scan.
+type XPNL struct {
+	TokenAddress           string  `json:"token-address" bson:"tokenaddress"`
+	TokenSymbol            string  `json:"token-symbol" bson:"tokensymbol"`
+	CountBuy               int     `json:"count-buy" bson:"countbuy"`
+	CountSell              int     `json:"count-sell" bson:"countsell"`
+	CountSellActual        int     `json:"count-sell-actual" bson:"countsellactual"`
+	TotalTokenBuy          float64 `json:"total-token-buy" bson:"totaltokenbuy"`
+	TotalTokenSell         float64 `json:"total-token-sell" bson:"totaltokensell"`
+	TotalTokenSellActual   float64 `json:"total-token-sell-actual" bson:"totaltokensellactual"`
+	TotalQuoteBuy          float64 `json:"total-quote-buy" bson:"totalquotebuy"`
+	TotalQuoteSell         float64 `json:"total-quote-sell" bson:"totalquotesell"`
+	TotalQuoteSellActual   float64 `json:"total-quote-sell-actual" bson:"totalquotesellactual"`
+	TokenHoldAmount        float64 `json:"token-hold-amount" bson:"tokenholdamount"`
+	TokenHoldQuoteAmount   float64 `json:"token-hold-quote-amount" bson:"tokenholdquoteamount"`
+	TokenHoldCostQuote     float64 `json:"token-hold-cost-quote" bson:"tokenholdcostquote"`
+	RealizedProfitQuote    float64 `json:"realized-profit-quote" bson:"realizedprofitquote"`
+	UnrealizedProfitQuote  float64 `json:"unrealized-profit-quote" bson:"unrealizedprofitquote"`
+	ProfitQuote            float64 `json:"profit-quote" bson:"profitquote"`
+	ProfitQuoteActual      float64 `json:"profit-quote-actual" bson:"profitquoteactual"`
+	TotalTokenInflow       float64 `json:"total-token-inflow" bson:"totaltokeninflow"`
+	TotalQuoteInflowCost   float64 `json:"total-quote-inflow-cost" bson:"totalquoteinflowcost"`
+	TotalTokenOutflow      float64 `json:"total-token-outflow" bson:"totaltokenoutflow"`
+	TotalQuoteOutflowCost  float64 `json:"total-quote-outflow-cost" bson:"totalquoteoutflowcost"`
+	UnexplainedTokenAmount float64 `json:"unexplained-token-amount" bson:"unexplainedtokenamount"`
+	UnexplainedRate        float64 `json:"unexplained-rate" bson:"unexplainedrate"`
+	TotalFees              float64 `json:"total-fees" bson:"totalfees"`
+	ProfitQuoteNet         float64 `json:"profit-quote-net" bson:"profitquotenet"`
+	ProfitQuoteActualNet   float64 `json:"profit-quote-actual-net" bson:"profitquoteactualnet"`
+	TotalUSDBuy            float64 `json:"total-usd-buy" bson:"totalusdbuy"`
+	TotalUSDSell           float64 `json:"total-usd-sell" bson:"totalusdsell"`
+	TokenHoldUSDAmount     float64 `json:"token-hold-usd-amount" bson:"tokenholdusdamount"`
+	TokenHoldCostUSD       float64 `json:"token-hold-cost-usd" bson:"tokenholdcostusd"`
+	RealizedProfitUSD      float64 `json:"realized-profit-usd" bson:"realizedprofitusd"`
+	UnrealizedProfitUSD    float64 `json:"unrealized-profit-usd" bson:"unrealizedprofitusd"`
+	ProfitUSD              float64 `json:"profit-usd" bson:"profitusd"`
+	ROIUSD                 float64 `json:"roi-usd" bson:"roiusd"`
+	TotalFeesUSD           float64 `json:"total-fees-usd" bson:"totalfeesusd"`
+	XPNL                   float64 `json:"xpnl" bson:"xpnl"`
+	XPNLRate               float64 `json:"xpnl-rate" bson:"xpnlrate"`
+	PriceQuoteFirstBuy     float64 `json:"price-quote-first-buy" bson:"pricequotefirstbuy"`
+	PriceQuoteBestSell     float64 `json:"price-quote-best-sell" bson:"pricequotebestsell"`
+	XPNLTrade              float64 `json:"xpnl-trade" bson:"xpnltrade"`
+	XPNLRateTrade          float64 `json:"xpnl-rate-trade" bson:"xpnlratetrade"`
+	StartTime              string  `json:"start-time" bson:"starttime"`
+	EndTime                string  `json:"end-time" bson:"endtime"`
+}
+
+// LostXPNL shares the XPNL layout; it only differs in which list it lands in.
+type LostXPNL XPNL
+
+type SummaryReview struct {
+	TotalPNLAmount       float64 `json:"total-pnl-amount" bson:"totalpnlamount"`
+	TotalPNLAmountActual float64 `json:"total-pnl-amount-actual" bson:"totalpnlamountactual"`
+	TotalFees            float64 `json:"total-fees" bson:"totalfees"`
+	TotalPNLAmountNet    float64 `json:"total-pnl-amount-net" bson:"totalpnlamountnet"`
+	TotalWin             int     `json:"total-win" bson:"totalwin"`
+	TotalLost            int     `json:"total-lost" bson:"totallost"`
+	WinRate              float64 `json:"win-rate" bson:"winrate"`
+	BigXPNL              int     `json:"big-xpnl" bson:"bigxpnl"`
+	RateBigXPNL          float64 `json:"rate-big-xpnl" bson:"ratebigxpnl"`
+	TotalUSDBuy          float64 `json:"total-usd-buy" bson:"totalusdbuy"`
+	TotalPNLAmountUSD    float64 `json:"total-pnl-amount-usd" bson:"totalpnlamountusd"`
+	ROIUSD               float64 `json:"roi-usd" bson:"roiusd"`
+	TotalWinUSD          int     `json:"total-win-usd" bson:"totalwinusd"`
+	TotalLostUSD         int     `json:"total-lost-usd" bson:"totallostusd"`
+	WinRateUSD           float64 `json:"win-rate-usd" bson:"winrateusd"`
+	TotalTruncated       int     `json:"total-truncated" bson:"totaltruncated"`
+}
+
+// EventTrade is a single normalized trade. Price, QuoteAmount and Fee are in
+// the quote asset of the enclosing PNL, the USD figures are taken at trade
+// time. Fee covers every network cost paid by the wallet for the event (base
+// and priority fees, tips, gas). Counterparty is the other wallet of a
+// transfer.
+type EventTrade struct {
+	TxHash         string  `json:"tx-hash" bson:"txhash"`
+	EventType      string  `json:"event-type" bson:"eventtype"`
+	Price          float64 `json:"price" bson:"price"`
+	PriceUSD       float64 `json:"price-usd" bson:"priceusd"`
+	QuoteAmount    float64 `json:"quote-amount" bson:"quoteamount"`
+	QuoteAmountUSD float64 `json:"quote-amount-usd" bson:"quoteamountusd"`
+	TokensAmount   float64 `json:"tokens-amount" bson:"tokensamount"`
+	Fee            float64 `json:"fee" bson:"fee"`
+	Counterparty   string  `json:"counterparty" bson:"counterparty"`
+	Timestamp      int64   `json:"timestamp" bson:"timestamp"`
+	DateTime       string  `json:"date-time" bson:"datetime"`
+}
+
+// LotMatch records how part of a sell was matched against an earlier buy.
+// Cost and Proceeds are in the quote asset, the USD figures are valued at
+// trade time and HoldingTime is in seconds.
+type LotMatch struct {
+	BuyTxHash   string  `json:"buy-tx-hash" bson:"buytxhash"`
+	SellTxHash  string  `json:"sell-tx-hash" bson:"selltxhash"`
+	Quantity    float64 `json:"quantity" bson:"quantity"`
+	Cost        float64 `json:"cost" bson:"cost"`
+	Proceeds    float64 `json:"proceeds" bson:"proceeds"`
+	Profit      float64 `json:"profit" bson:"profit"`
+	CostUSD     float64 `json:"cost-usd" bson:"costusd"`
+	ProceedsUSD float64 `json:"proceeds-usd" bson:"proceedsusd"`
+	ProfitUSD   float64 `json:"profit-usd" bson:"profitusd"`
+	BuyTime     int64   `json:"buy-time" bson:"buytime"`
+	SellTime    int64   `json:"sell-time" bson:"selltime"`
+	HoldingTime int64   `json:"holding-time" bson:"holdingtime"`
+}
+
+// ExcludedToken is a traded token that was left out of the scan, and why.
+type ExcludedToken struct {
+	TokenAddress string `json:"token-address" bson:"tokenaddress"`
+	TokenSymbol  string `json:"token-symbol" bson:"tokensymbol"`
+	Category     string `json:"category" bson:"category"`
+}
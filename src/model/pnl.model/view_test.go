@@ -0,0 +1,63 @@
+package pnlmodel_test
+
+import (
+	"reflect"
+	"testing"
+
+	ethmodel "pnl-scan-tool/src/model/eth.model"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	solmodel "pnl-scan-tool/src/model/sol.model"
+)
+
+// fill sets every field of v to a non-zero value, with one element in each
+// slice.
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fill(v.Field(i))
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Float64:
+		v.SetFloat(1)
+	}
+}
+
+// assertFilled reports every field of v left at its zero value.
+func assertFilled(t *testing.T, v reflect.Value, path string) {
+	t.Helper()
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			assertFilled(t, v.Field(i), path+"."+v.Type().Field(i).Name)
+		}
+	case reflect.Slice:
+		if v.Len() != 1 {
+			t.Errorf("%s has %d elements, want 1", path, v.Len())
+			return
+		}
+
+		assertFilled(t, v.Index(0), path+"[0]")
+	default:
+		if v.IsZero() {
+			t.Errorf("%s is not filled", path)
+		}
+	}
+}
+
+func TestFromPNLFillsEveryViewField(t *testing.T) {
+	var p pnlmodel.PNL
+	fill(reflect.ValueOf(&p).Elem())
+
+	assertFilled(t, reflect.ValueOf(solmodel.FromPNL(&p)).Elem(), "solmodel.PNL")
+	assertFilled(t, reflect.ValueOf(ethmodel.FromPNL(&p)).Elem(), "ethmodel.PNL")
+}
@@ -0,0 +1,102 @@
+package schedulemodel
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Schedule actions.
+const (
+	ActionRescan = "rescan"
+)
+
+// Outcomes of a scheduled run.
+const (
+	OutcomeDone      = "done"
+	OutcomeFailed    = "failed"
+	OutcomeCancelled = "cancelled"
+)
+
+// Schedule is a named, recurring scan of a set of stored wallets, and the
+// record of its last run.
+type Schedule struct {
+	Name string `json:"name" bson:"_id"`
+	// Spec is when it runs: "@every 6h", "@daily" or a cron expression.
+	Spec    string `json:"spec" bson:"spec"`
+	Action  string `json:"action" bson:"action"`
+	Chain   string `json:"chain" bson:"chain"`
+	Enabled bool   `json:"enabled" bson:"enabled"`
+	// Mode is the rescan mode: incremental, full or backfill-usd.
+	Mode string `json:"mode,omitempty" bson:"mode,omitempty"`
+	// Filter selects the all-time wallets rescanned, all of them when empty.
+	Filter    Filter `json:"filter,omitempty" bson:"filter,omitempty"`
+	CreatedAt int64  `json:"created-at,omitempty" bson:"createdat,omitempty"`
+
+	LastRun int64 `json:"last-run,omitempty" bson:"lastrun,omitempty"`
+	// LastDuration is how long the last run took, in seconds.
+	LastDuration int64  `json:"last-duration,omitempty" bson:"lastduration,omitempty"`
+	LastOutcome  string `json:"last-outcome,omitempty" bson:"lastoutcome,omitempty"`
+	LastError    string `json:"last-error,omitempty" bson:"lasterror,omitempty"`
+	LastJob      string `json:"last-job,omitempty" bson:"lastjob,omitempty"`
+	NextRun      int64  `json:"next-run,omitempty" bson:"nextrun,omitempty"`
+
+	// LockedBy is the process running the schedule, until LockedUntil.
+	LockedBy    string `json:"locked-by,omitempty" bson:"lockedby,omitempty"`
+	LockedUntil int64  `json:"locked-until,omitempty" bson:"lockeduntil,omitempty"`
+}
+
+// Filter is a MongoDB query on the stored wallets. It is stored as extended
+// JSON text rather than a document, so that its operators ($gt, ...) are not
+// field names of the schedule, which MongoDB only accepts from 5.0 on.
+type Filter bson.M
+
+func (f Filter) IsZero() bool {
+	return len(f) == 0
+}
+
+func (f Filter) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if f == nil {
+		return bsontype.Null, nil, nil
+	}
+
+	data, err := bson.MarshalExtJSON(bson.M(f), false, false)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return bson.MarshalValue(string(data))
+}
+
+// UnmarshalBSONValue also reads the filters stored as documents.
+func (f *Filter) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var query bson.M
+
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		*f = nil
+		return nil
+	case bsontype.String:
+		var text string
+
+		if err := bson.UnmarshalValue(t, data, &text); err != nil {
+			return err
+		}
+
+		if err := bson.UnmarshalExtJSON([]byte(text), false, &query); err != nil {
+			return fmt.Errorf("invalid schedule filter: %w", err)
+		}
+	case bsontype.EmbeddedDocument:
+		if err := bson.Unmarshal(data, &query); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid schedule filter of type %s", t)
+	}
+
+	*f = Filter(query)
+
+	return nil
+}
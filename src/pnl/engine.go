@@ -0,0 +1,287 @@
+package pnl
+
+import (
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+const epsilon = 1e-9
+
+// bigXPNLThreshold is the multiple from which a token counts as a big win.
+const bigXPNLThreshold = 2.0
+
+// Options tunes how the engine computes PnL. The zero value is usable.
+type Options struct {
+	// CostBasis selects how sells are matched to buys. Defaults to FIFO.
+	CostBasis CostBasis
+	// InflowPolicies sets the cost basis of tokens received by transfer,
+	// airdrop or mint, keyed by event type. Missing kinds use the defaults:
+	// market price for transfers and zero cost for airdrops and mints.
+	InflowPolicies map[string]InflowPolicy
+	// InheritCost looks up the sender's cost for the InheritedCost policy.
+	InheritCost CostResolver
+	// NativePriceUSD is the USD price of the quote asset. It values the fees
+	// of events that carry neither a quote amount nor a price, such as
+	// transfers.
+	NativePriceUSD float64
+}
+
+// Engine accumulates per-token trade streams into a single chain-neutral PNL.
+type Engine struct {
+	options Options
+	result  pnlmodel.PNL
+}
+
+// NewEngine creates an engine for the given wallet whose figures are
+// expressed in quoteAsset.
+func NewEngine(chain string, walletAddress string, quoteAsset string, options Options) *Engine {
+	if options.CostBasis == "" {
+		options.CostBasis = FIFO
+	}
+
+	return &Engine{
+		options: options,
+		result: pnlmodel.PNL{
+			WalletAddress: walletAddress,
+			Chain:         chain,
+			QuoteAsset:    quoteAsset,
+			CostBasis:     string(options.CostBasis),
+		},
+	}
+}
+
+// AddToken computes the PnL of one token and records it in the result.
+//
+// The trades in history must be ordered oldest first. Whatever is still held
+// is valued at the history's mark prices. It returns nil when the history has
+// no trades.
+func (e *Engine) AddToken(history pnlmodel.TradeHistory) *pnlmodel.XPNL {
+	if len(history.EventTrades) == 0 {
+		return nil
+	}
+
+	history.StartTime = history.EventTrades[0].DateTime
+	history.EndTime = history.EventTrades[len(history.EventTrades)-1].DateTime
+
+	entry := pnlmodel.XPNL{
+		TokenAddress: history.TokenAddress,
+		TokenSymbol:  history.TokenSymbol,
+		StartTime:    history.StartTime,
+		EndTime:      history.EndTime,
+	}
+
+	book := newLotBook(e.options.CostBasis)
+
+	for _, eventTrade := range history.EventTrades {
+		entry.TotalFees += eventTrade.Fee
+		entry.TotalFeesUSD += eventTrade.Fee * e.quotePriceUSD(eventTrade)
+
+		switch eventTrade.EventType {
+		case pnlmodel.EventBuy:
+			if entry.PriceQuoteFirstBuy == 0 {
+				entry.PriceQuoteFirstBuy = eventTrade.Price
+			}
+
+			entry.TotalTokenBuy += eventTrade.TokensAmount
+			entry.TotalQuoteBuy += eventTrade.QuoteAmount
+			entry.TotalUSDBuy += eventTrade.QuoteAmountUSD
+
+			book.add(eventTrade)
+
+			entry.CountBuy++
+		case pnlmodel.EventSell:
+			if eventTrade.Price > entry.PriceQuoteBestSell {
+				entry.PriceQuoteBestSell = eventTrade.Price
+			}
+
+			matches, unmatched := book.match(eventTrade)
+			entry.UnexplainedTokenAmount += unmatched
+
+			for _, match := range matches {
+				entry.TotalQuoteSell += match.Proceeds
+				entry.TotalTokenSell += match.Quantity
+				entry.RealizedProfitQuote += match.Profit
+				entry.TotalUSDSell += match.ProceedsUSD
+				entry.RealizedProfitUSD += match.ProfitUSD
+			}
+
+			if len(matches) != 0 {
+				entry.CountSell++
+			}
+
+			history.LotMatches = append(history.LotMatches, matches...)
+
+			entry.TotalQuoteSellActual += eventTrade.QuoteAmount
+			entry.TotalTokenSellActual += eventTrade.TokensAmount
+
+			entry.CountSellActual++
+		case pnlmodel.EventTransferIn, pnlmodel.EventAirdrop, pnlmodel.EventMint:
+			inflow := e.inflowCost(history.TokenAddress, eventTrade)
+
+			entry.TotalTokenInflow += inflow.TokensAmount
+			entry.TotalQuoteInflowCost += inflow.QuoteAmount
+
+			book.add(inflow)
+		case pnlmodel.EventTransferOut, pnlmodel.EventBurn:
+			// Outflows leave the wallet without proceeds. A transfer keeps its
+			// value elsewhere so it is removed at cost, a burn is a realized loss.
+			outflow := eventTrade
+			outflow.QuoteAmount = 0
+			outflow.QuoteAmountUSD = 0
+
+			matches, unmatched := book.match(outflow)
+			entry.UnexplainedTokenAmount += unmatched
+			entry.TotalTokenOutflow += eventTrade.TokensAmount
+
+			for _, match := range matches {
+				if eventTrade.EventType == pnlmodel.EventBurn {
+					entry.RealizedProfitQuote += match.Profit
+					entry.RealizedProfitUSD += match.ProfitUSD
+				} else {
+					entry.TotalQuoteOutflowCost += match.Cost
+				}
+			}
+
+			if eventTrade.EventType == pnlmodel.EventBurn {
+				history.LotMatches = append(history.LotMatches, matches...)
+			}
+		}
+	}
+
+	if disposed := entry.TotalTokenSellActual + entry.TotalTokenOutflow; disposed > 0 {
+		entry.UnexplainedRate = (entry.UnexplainedTokenAmount / disposed) * 100
+	}
+
+	entry.TokenHoldAmount = book.quantity()
+	entry.TokenHoldCostQuote = book.cost()
+	entry.TokenHoldQuoteAmount = history.MarkPriceQuote * entry.TokenHoldAmount
+	entry.UnrealizedProfitQuote = entry.TokenHoldQuoteAmount - entry.TokenHoldCostQuote
+
+	entry.TokenHoldUSDAmount = history.MarkPriceUSD * entry.TokenHoldAmount
+	entry.TokenHoldCostUSD = book.costUSD()
+	entry.UnrealizedProfitUSD = entry.TokenHoldUSDAmount - entry.TokenHoldCostUSD
+	entry.ProfitUSD = entry.RealizedProfitUSD + entry.UnrealizedProfitUSD
+
+	if entry.TotalUSDBuy != 0 {
+		entry.ROIUSD = (entry.ProfitUSD / entry.TotalUSDBuy) * 100
+	}
+
+	entry.ProfitQuote = entry.RealizedProfitQuote + entry.UnrealizedProfitQuote
+	entry.ProfitQuoteActual = entry.TotalQuoteSellActual - entry.TotalQuoteBuy + entry.TokenHoldQuoteAmount -
+		entry.TotalQuoteInflowCost + entry.TotalQuoteOutflowCost
+
+	entry.ProfitQuoteNet = entry.ProfitQuote - entry.TotalFees
+	entry.ProfitQuoteActualNet = entry.ProfitQuoteActual - entry.TotalFees
+
+	// Buys without a quote amount or price, e.g. from a source that carries
+	// none, leave the multiples at 0
+	if entry.CountBuy != 0 && entry.TotalQuoteBuy != 0 {
+		entry.XPNL = (entry.TotalQuoteSell + entry.TokenHoldQuoteAmount) / entry.TotalQuoteBuy
+		entry.XPNLRate = (entry.ProfitQuote / entry.TotalQuoteBuy) * 100
+	}
+
+	if entry.CountBuy != 0 && entry.PriceQuoteFirstBuy != 0 {
+		entry.XPNLTrade = entry.PriceQuoteBestSell / entry.PriceQuoteFirstBuy
+		entry.XPNLRateTrade = ((entry.PriceQuoteBestSell - entry.PriceQuoteFirstBuy) / entry.PriceQuoteFirstBuy) * 100
+	}
+
+	summary := &e.result.SummaryReview
+
+	e.result.TradeHistory = append(e.result.TradeHistory, history)
+	summary.TotalPNLAmount += entry.ProfitQuote
+	summary.TotalPNLAmountActual += entry.ProfitQuoteActual
+	summary.TotalFees += entry.TotalFees
+	summary.TotalPNLAmountNet += entry.ProfitQuoteNet
+
+	if history.Truncated {
+		summary.TotalTruncated++
+	}
+
+	// A token only counts as a win if it is still profitable after fees.
+	if entry.ProfitQuoteNet > epsilon {
+		summary.TotalWin++
+		e.result.XPNLs = append(e.result.XPNLs, entry)
+	} else {
+		summary.TotalLost++
+		e.result.LostXPNLs = append(e.result.LostXPNLs, pnlmodel.LostXPNL(entry))
+	}
+
+	summary.WinRate = (float64(summary.TotalWin) / float64(summary.TotalWin+summary.TotalLost)) * 100.0
+
+	summary.TotalUSDBuy += entry.TotalUSDBuy
+	summary.TotalPNLAmountUSD += entry.ProfitUSD
+
+	if summary.TotalUSDBuy != 0 {
+		summary.ROIUSD = (summary.TotalPNLAmountUSD / summary.TotalUSDBuy) * 100
+	}
+
+	if entry.ProfitUSD-entry.TotalFeesUSD > epsilon {
+		summary.TotalWinUSD++
+	} else {
+		summary.TotalLostUSD++
+	}
+
+	summary.WinRateUSD = (float64(summary.TotalWinUSD) / float64(summary.TotalWinUSD+summary.TotalLostUSD)) * 100.0
+
+	return &entry
+}
+
+// quotePriceUSD returns the USD price of the quote asset at the time of
+// eventTrade, falling back to NativePriceUSD when the event carries none.
+func (e *Engine) quotePriceUSD(eventTrade pnlmodel.EventTrade) float64 {
+	if eventTrade.QuoteAmount != 0 && eventTrade.QuoteAmountUSD != 0 {
+		return eventTrade.QuoteAmountUSD / eventTrade.QuoteAmount
+	}
+
+	if eventTrade.Price != 0 && eventTrade.PriceUSD != 0 {
+		return eventTrade.PriceUSD / eventTrade.Price
+	}
+
+	return e.options.NativePriceUSD
+}
+
+// Exclude records a token that was left out of the scan and why.
+func (e *Engine) Exclude(tokenAddress string, tokenSymbol string, category string) {
+	e.result.Excluded = append(e.result.Excluded, pnlmodel.ExcludedToken{
+		TokenAddress: tokenAddress,
+		TokenSymbol:  tokenSymbol,
+		Category:     category,
+	})
+}
+
+// Result finalizes the summary and returns the accumulated PNL.
+func (e *Engine) Result() *pnlmodel.PNL {
+	summary := &e.result.SummaryReview
+
+	totalBigXPNL := 0
+
+	for _, xpnl := range e.result.XPNLs {
+		if xpnl.XPNL >= bigXPNLThreshold || xpnl.XPNLTrade >= bigXPNLThreshold {
+			totalBigXPNL++
+		}
+	}
+
+	for _, xpnl := range e.result.LostXPNLs {
+		if xpnl.XPNLTrade >= bigXPNLThreshold {
+			totalBigXPNL++
+		}
+	}
+
+	summary.BigXPNL = totalBigXPNL
+	summary.RateBigXPNL = 0
+
+	if total := len(e.result.XPNLs) + len(e.result.LostXPNLs); total != 0 {
+		summary.RateBigXPNL = float64(totalBigXPNL) / float64(total) * 100.0
+	}
+
+	return &e.result
+}
+
+// QuoteAsset returns the asset every figure is expressed in.
+func (e *Engine) QuoteAsset() string {
+	return e.result.QuoteAsset
+}
+
+// Summary returns the running summary of the tokens added so far.
+func (e *Engine) Summary() pnlmodel.SummaryReview {
+	return e.result.SummaryReview
+}
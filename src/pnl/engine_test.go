@@ -0,0 +1,129 @@
+package pnl
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+// quoteUSD is the USD price of the quote asset in the test trades.
+const quoteUSD = 100.0
+
+// trade is an event of tokens tokens for quote of the quote asset.
+func trade(eventType string, tokens float64, quote float64) pnlmodel.EventTrade {
+	return pnlmodel.EventTrade{
+		EventType:      eventType,
+		Price:          quote / tokens,
+		PriceUSD:       quote / tokens * quoteUSD,
+		QuoteAmount:    quote,
+		QuoteAmountUSD: quote * quoteUSD,
+		TokensAmount:   tokens,
+	}
+}
+
+// history numbers the trades tx0, tx1, ... one second apart, oldest first.
+func history(trades ...pnlmodel.EventTrade) pnlmodel.TradeHistory {
+	h := pnlmodel.TradeHistory{TokenAddress: "token"}
+
+	for i, eventTrade := range trades {
+		eventTrade.TxHash = "tx" + strconv.Itoa(i)
+		eventTrade.Timestamp = int64(i)
+		h.EventTrades = append(h.EventTrades, eventTrade)
+	}
+
+	return h
+}
+
+func assertFloat(t *testing.T, name string, got float64, want float64) {
+	t.Helper()
+
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestAddTokenCountsWinsAfterFees(t *testing.T) {
+	tests := []struct {
+		name    string
+		history pnlmodel.TradeHistory
+		win     bool
+	}{
+		{
+			name: "profit above fees",
+			history: history(
+				trade(pnlmodel.EventBuy, 100, 1),
+				trade(pnlmodel.EventSell, 100, 2),
+			),
+			win: true,
+		},
+		{
+			name: "profit eaten by fees",
+			history: history(
+				trade(pnlmodel.EventBuy, 100, 1),
+				pnlmodel.EventTrade{EventType: pnlmodel.EventSell, TokensAmount: 100, QuoteAmount: 1.01, Price: 0.0101, Fee: 0.02},
+			),
+			win: false,
+		},
+		{
+			// The sell proceeds only show in the actual profit, which is
+			// not what decides a win
+			name: "unmatched sell",
+			history: history(
+				pnlmodel.EventTrade{EventType: pnlmodel.EventSell, TokensAmount: 100, QuoteAmount: 1, Price: 0.01, Fee: 0.01},
+			),
+			win: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine("sol", "wallet", "SOL", Options{})
+			engine.AddToken(tt.history)
+
+			result := engine.Result()
+
+			if got := len(result.XPNLs) == 1; got != tt.win {
+				t.Errorf("win = %v, want %v (summary %+v)", got, tt.win, result.SummaryReview)
+			}
+		})
+	}
+}
+
+func TestAddTokenPricesFeesInUSD(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		trade   pnlmodel.EventTrade
+		want    float64
+	}{
+		{
+			name:  "from the quote amount",
+			trade: trade(pnlmodel.EventBuy, 100, 1),
+			want:  0.01 * quoteUSD,
+		},
+		{
+			name:  "from the price",
+			trade: pnlmodel.EventTrade{EventType: pnlmodel.EventTransferIn, TokensAmount: 100, Price: 0.01, PriceUSD: 2},
+			want:  0.01 * 200,
+		},
+		{
+			name:    "from the native price",
+			options: Options{NativePriceUSD: 150},
+			trade:   pnlmodel.EventTrade{EventType: pnlmodel.EventTransferOut, TokensAmount: 100},
+			want:    0.01 * 150,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.trade.Fee = 0.01
+
+			engine := NewEngine("sol", "wallet", "SOL", tt.options)
+			entry := engine.AddToken(history(tt.trade))
+
+			assertFloat(t, "TotalFeesUSD", entry.TotalFeesUSD, tt.want)
+		})
+	}
+}
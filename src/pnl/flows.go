@@ -0,0 +1,99 @@
+package pnl
+
+import (
+	"fmt"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"strings"
+)
+
+// InflowPolicy selects the cost basis given to tokens that were received
+// without a swap.
+type InflowPolicy string
+
+const (
+	// ZeroCost books received tokens at no cost.
+	ZeroCost InflowPolicy = "zero"
+	// MarketCost books received tokens at the market price at receipt.
+	MarketCost InflowPolicy = "market"
+	// InheritedCost books received tokens at the cost the sending wallet
+	// holds them at, falling back to the market price when it is unknown.
+	InheritedCost InflowPolicy = "inherit"
+)
+
+// defaultInflowPolicies is used for every inflow kind not set in Options.
+var defaultInflowPolicies = map[string]InflowPolicy{
+	pnlmodel.EventTransferIn: MarketCost,
+	pnlmodel.EventAirdrop:    ZeroCost,
+	pnlmodel.EventMint:       ZeroCost,
+}
+
+// CostResolver returns the per-token cost, in quote asset and USD, at which
+// sender holds token. ok is false when it is unknown.
+type CostResolver func(sender string, token string) (cost float64, costUSD float64, ok bool)
+
+// ParseInflowPolicies parses a list of policies such as
+// "transfer_in=inherit,airdrop=zero".
+func ParseInflowPolicies(value string) (map[string]InflowPolicy, error) {
+	policies := make(map[string]InflowPolicy)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kind, name, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid inflow policy: %s", pair)
+		}
+
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if _, known := defaultInflowPolicies[kind]; !known {
+			return nil, fmt.Errorf("unknown inflow kind: %s", kind)
+		}
+
+		policy := InflowPolicy(strings.ToLower(strings.TrimSpace(name)))
+		switch policy {
+		case ZeroCost, MarketCost, InheritedCost:
+		default:
+			return nil, fmt.Errorf("unknown inflow policy: %s", name)
+		}
+
+		policies[kind] = policy
+	}
+
+	return policies, nil
+}
+
+// inflowPolicy returns the policy for an inflow kind.
+func (o Options) inflowPolicy(kind string) InflowPolicy {
+	if policy, ok := o.InflowPolicies[kind]; ok {
+		return policy
+	}
+	return defaultInflowPolicies[kind]
+}
+
+// inflowCost books an inflow at the cost its policy gives it, so that it can
+// be added to the lot book like a buy.
+func (e *Engine) inflowCost(tokenAddress string, eventTrade pnlmodel.EventTrade) pnlmodel.EventTrade {
+	policy := e.options.inflowPolicy(eventTrade.EventType)
+
+	if policy == InheritedCost && e.options.InheritCost != nil && eventTrade.Counterparty != "" {
+		if cost, costUSD, ok := e.options.InheritCost(eventTrade.Counterparty, tokenAddress); ok {
+			eventTrade.QuoteAmount = cost * eventTrade.TokensAmount
+			eventTrade.QuoteAmountUSD = costUSD * eventTrade.TokensAmount
+			return eventTrade
+		}
+	}
+
+	if policy == ZeroCost {
+		eventTrade.QuoteAmount = 0
+		eventTrade.QuoteAmountUSD = 0
+		return eventTrade
+	}
+
+	eventTrade.QuoteAmount = eventTrade.Price * eventTrade.TokensAmount
+	eventTrade.QuoteAmountUSD = eventTrade.PriceUSD * eventTrade.TokensAmount
+
+	return eventTrade
+}
@@ -0,0 +1,164 @@
+package pnl
+
+import (
+	"testing"
+
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+// holdingCost resolves the cost of a sender from its scan result, the way
+// the stored scans are read during a wallet scan.
+func holdingCost(results map[string]*pnlmodel.PNL) CostResolver {
+	return func(sender string, token string) (float64, float64, bool) {
+		stored, ok := results[sender]
+
+		if !ok {
+			return 0, 0, false
+		}
+
+		entries := stored.XPNLs
+
+		for _, lost := range stored.LostXPNLs {
+			entries = append(entries, pnlmodel.XPNL(lost))
+		}
+
+		for _, entry := range entries {
+			if entry.TokenAddress == token && entry.TokenHoldAmount > 0 {
+				return entry.TokenHoldCostQuote / entry.TokenHoldAmount, entry.TokenHoldCostUSD / entry.TokenHoldAmount, true
+			}
+		}
+
+		return 0, 0, false
+	}
+}
+
+func TestAddTokenBooksInflows(t *testing.T) {
+	// The sender bought its tokens at 0.005
+	sender := NewEngine("sol", "sender", "SOL", Options{})
+	sender.AddToken(history(trade(pnlmodel.EventBuy, 200, 1)))
+
+	resolver := holdingCost(map[string]*pnlmodel.PNL{"sender": sender.Result()})
+
+	// Every inflow is of 100 tokens at a market price of 0.02
+	inflow := func(eventType string, counterparty string) pnlmodel.EventTrade {
+		eventTrade := trade(eventType, 100, 2)
+		eventTrade.QuoteAmount = 0
+		eventTrade.QuoteAmountUSD = 0
+		eventTrade.Counterparty = counterparty
+
+		return eventTrade
+	}
+
+	tests := []struct {
+		name    string
+		options Options
+		inflow  pnlmodel.EventTrade
+		cost    float64
+	}{
+		{
+			name:   "transfer at market price by default",
+			inflow: inflow(pnlmodel.EventTransferIn, "sender"),
+			cost:   2,
+		},
+		{
+			name:   "airdrop at zero cost by default",
+			inflow: inflow(pnlmodel.EventAirdrop, ""),
+			cost:   0,
+		},
+		{
+			name:   "mint at zero cost by default",
+			inflow: inflow(pnlmodel.EventMint, ""),
+			cost:   0,
+		},
+		{
+			name:    "transfer at zero cost",
+			options: Options{InflowPolicies: map[string]InflowPolicy{pnlmodel.EventTransferIn: ZeroCost}},
+			inflow:  inflow(pnlmodel.EventTransferIn, "sender"),
+			cost:    0,
+		},
+		{
+			name:    "airdrop at market price",
+			options: Options{InflowPolicies: map[string]InflowPolicy{pnlmodel.EventAirdrop: MarketCost}},
+			inflow:  inflow(pnlmodel.EventAirdrop, ""),
+			cost:    2,
+		},
+		{
+			name:    "transfer inheriting the cost of the sender",
+			options: Options{InflowPolicies: map[string]InflowPolicy{pnlmodel.EventTransferIn: InheritedCost}, InheritCost: resolver},
+			inflow:  inflow(pnlmodel.EventTransferIn, "sender"),
+			cost:    0.5,
+		},
+		{
+			name:    "transfer from a sender without a scan",
+			options: Options{InflowPolicies: map[string]InflowPolicy{pnlmodel.EventTransferIn: InheritedCost}, InheritCost: resolver},
+			inflow:  inflow(pnlmodel.EventTransferIn, "unknown"),
+			cost:    2,
+		},
+		{
+			name:    "transfer without a counterparty",
+			options: Options{InflowPolicies: map[string]InflowPolicy{pnlmodel.EventTransferIn: InheritedCost}, InheritCost: resolver},
+			inflow:  inflow(pnlmodel.EventTransferIn, ""),
+			cost:    2,
+		},
+		{
+			name:    "transfer without a resolver",
+			options: Options{InflowPolicies: map[string]InflowPolicy{pnlmodel.EventTransferIn: InheritedCost}},
+			inflow:  inflow(pnlmodel.EventTransferIn, "sender"),
+			cost:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine("sol", "wallet", "SOL", tt.options)
+			entry := engine.AddToken(history(tt.inflow, trade(pnlmodel.EventSell, 100, 3)))
+
+			assertFloat(t, "TotalTokenInflow", entry.TotalTokenInflow, 100)
+			assertFloat(t, "TotalQuoteInflowCost", entry.TotalQuoteInflowCost, tt.cost)
+			assertFloat(t, "RealizedProfitQuote", entry.RealizedProfitQuote, 3-tt.cost)
+			assertFloat(t, "RealizedProfitUSD", entry.RealizedProfitUSD, (3-tt.cost)*quoteUSD)
+			assertFloat(t, "ProfitQuoteActual", entry.ProfitQuoteActual, 3-tt.cost)
+		})
+	}
+}
+
+func TestParseInflowPolicies(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]InflowPolicy
+		wantErr bool
+	}{
+		{value: "", want: map[string]InflowPolicy{}},
+		{
+			value: "transfer_in=inherit, Airdrop=MARKET",
+			want:  map[string]InflowPolicy{pnlmodel.EventTransferIn: InheritedCost, pnlmodel.EventAirdrop: MarketCost},
+		},
+		{value: "transfer_in", wantErr: true},
+		{value: "swap=zero", wantErr: true},
+		{value: "mint=free", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseInflowPolicies(tt.value)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+
+			for kind, policy := range tt.want {
+				if got[kind] != policy {
+					t.Errorf("%s = %s, want %s", kind, got[kind], policy)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package pnl
+
+import (
+	"fmt"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"strings"
+)
+
+// CostBasis selects how sells are matched against earlier buys.
+type CostBasis string
+
+const (
+	FIFO        CostBasis = "fifo"
+	LIFO        CostBasis = "lifo"
+	AverageCost CostBasis = "average"
+)
+
+// ParseCostBasis parses a cost basis name as given on the command line.
+func ParseCostBasis(name string) (CostBasis, error) {
+	switch CostBasis(strings.ToLower(name)) {
+	case FIFO:
+		return FIFO, nil
+	case LIFO:
+		return LIFO, nil
+	case AverageCost, "avg":
+		return AverageCost, nil
+	}
+
+	return "", fmt.Errorf("unknown cost basis: %s", name)
+}
+
+// lot is a quantity of tokens still held from a single buy.
+type lot struct {
+	txHash          string
+	timestamp       int64
+	quantity        float64
+	costPerToken    float64
+	costUSDPerToken float64
+}
+
+// lotBook holds the open lots of one token.
+type lotBook struct {
+	method CostBasis
+	lots   []lot
+}
+
+func newLotBook(method CostBasis) *lotBook {
+	return &lotBook{method: method}
+}
+
+// add opens a lot for a buy.
+func (b *lotBook) add(eventTrade pnlmodel.EventTrade) {
+	if eventTrade.TokensAmount <= 0 {
+		return
+	}
+
+	b.lots = append(b.lots, lot{
+		txHash:          eventTrade.TxHash,
+		timestamp:       eventTrade.Timestamp,
+		quantity:        eventTrade.TokensAmount,
+		costPerToken:    eventTrade.QuoteAmount / eventTrade.TokensAmount,
+		costUSDPerToken: eventTrade.QuoteAmountUSD / eventTrade.TokensAmount,
+	})
+
+	if b.method == AverageCost {
+		quantity := b.quantity()
+		average := b.cost() / quantity
+		averageUSD := b.costUSD() / quantity
+
+		for i := range b.lots {
+			b.lots[i].costPerToken = average
+			b.lots[i].costUSDPerToken = averageUSD
+		}
+	}
+}
+
+// match consumes open lots for a sell. It returns the matched pairs and the
+// part of the sell that could not be matched against any held lot.
+func (b *lotBook) match(eventTrade pnlmodel.EventTrade) ([]pnlmodel.LotMatch, float64) {
+	var matches []pnlmodel.LotMatch
+
+	if eventTrade.TokensAmount <= 0 {
+		return nil, 0
+	}
+
+	proceedsPerToken := eventTrade.QuoteAmount / eventTrade.TokensAmount
+	proceedsUSDPerToken := eventTrade.QuoteAmountUSD / eventTrade.TokensAmount
+	remaining := eventTrade.TokensAmount
+
+	for remaining > epsilon && len(b.lots) > 0 {
+		index := 0
+		if b.method == LIFO {
+			index = len(b.lots) - 1
+		}
+
+		open := &b.lots[index]
+
+		quantity := open.quantity
+		if remaining < quantity {
+			quantity = remaining
+		}
+
+		cost := quantity * open.costPerToken
+		proceeds := quantity * proceedsPerToken
+		costUSD := quantity * open.costUSDPerToken
+		proceedsUSD := quantity * proceedsUSDPerToken
+
+		matches = append(matches, pnlmodel.LotMatch{
+			BuyTxHash:   open.txHash,
+			SellTxHash:  eventTrade.TxHash,
+			Quantity:    quantity,
+			Cost:        cost,
+			Proceeds:    proceeds,
+			Profit:      proceeds - cost,
+			CostUSD:     costUSD,
+			ProceedsUSD: proceedsUSD,
+			ProfitUSD:   proceedsUSD - costUSD,
+			BuyTime:     open.timestamp,
+			SellTime:    eventTrade.Timestamp,
+			HoldingTime: eventTrade.Timestamp - open.timestamp,
+		})
+
+		open.quantity -= quantity
+		remaining -= quantity
+
+		if open.quantity <= epsilon {
+			b.lots = append(b.lots[:index], b.lots[index+1:]...)
+		}
+	}
+
+	if remaining < epsilon {
+		remaining = 0
+	}
+
+	return matches, remaining
+}
+
+// quantity returns the number of tokens still held.
+func (b *lotBook) quantity() float64 {
+	var total float64
+	for _, open := range b.lots {
+		total += open.quantity
+	}
+	return total
+}
+
+// cost returns the cost basis of the tokens still held.
+func (b *lotBook) cost() float64 {
+	var total float64
+	for _, open := range b.lots {
+		total += open.quantity * open.costPerToken
+	}
+	return total
+}
+
+// costUSD returns the USD cost basis of the tokens still held.
+func (b *lotBook) costUSD() float64 {
+	var total float64
+	for _, open := range b.lots {
+		total += open.quantity * open.costUSDPerToken
+	}
+	return total
+}
@@ -0,0 +1,154 @@
+package pnl
+
+import (
+	"testing"
+
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+)
+
+func TestAddTokenMatchesLots(t *testing.T) {
+	// Two buys at 0.01 and 0.03, then a sell of 150 tokens at 0.04 that
+	// consumes the first lot matched and part of the other
+	trades := history(
+		trade(pnlmodel.EventBuy, 100, 1),
+		trade(pnlmodel.EventBuy, 100, 3),
+		trade(pnlmodel.EventSell, 150, 6),
+	)
+
+	type match struct {
+		buy      string
+		quantity float64
+		cost     float64
+	}
+
+	tests := []struct {
+		costBasis CostBasis
+		matches   []match
+		realized  float64
+		holdCost  float64
+	}{
+		{
+			costBasis: FIFO,
+			matches:   []match{{"tx0", 100, 1}, {"tx1", 50, 1.5}},
+			realized:  3.5,
+			holdCost:  1.5,
+		},
+		{
+			costBasis: LIFO,
+			matches:   []match{{"tx1", 100, 3}, {"tx0", 50, 0.5}},
+			realized:  2.5,
+			holdCost:  0.5,
+		},
+		{
+			costBasis: AverageCost,
+			matches:   []match{{"tx0", 100, 2}, {"tx1", 50, 1}},
+			realized:  3,
+			holdCost:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.costBasis), func(t *testing.T) {
+			engine := NewEngine("sol", "wallet", "SOL", Options{CostBasis: tt.costBasis})
+			entry := engine.AddToken(trades)
+
+			matches := engine.Result().TradeHistory[0].LotMatches
+
+			if len(matches) != len(tt.matches) {
+				t.Fatalf("got %d lot matches, want %d: %+v", len(matches), len(tt.matches), matches)
+			}
+
+			for i, want := range tt.matches {
+				got := matches[i]
+
+				if got.BuyTxHash != want.buy || got.SellTxHash != "tx2" {
+					t.Errorf("match %d = %s -> %s, want %s -> tx2", i, got.BuyTxHash, got.SellTxHash, want.buy)
+				}
+
+				assertFloat(t, "Quantity", got.Quantity, want.quantity)
+				assertFloat(t, "Cost", got.Cost, want.cost)
+				assertFloat(t, "Proceeds", got.Proceeds, want.quantity*0.04)
+				assertFloat(t, "CostUSD", got.CostUSD, want.cost*quoteUSD)
+			}
+
+			assertFloat(t, "RealizedProfitQuote", entry.RealizedProfitQuote, tt.realized)
+			assertFloat(t, "TokenHoldAmount", entry.TokenHoldAmount, 50)
+			assertFloat(t, "TokenHoldCostQuote", entry.TokenHoldCostQuote, tt.holdCost)
+			assertFloat(t, "UnexplainedTokenAmount", entry.UnexplainedTokenAmount, 0)
+		})
+	}
+}
+
+func TestAddTokenConsumesLotsPartially(t *testing.T) {
+	engine := NewEngine("sol", "wallet", "SOL", Options{})
+	entry := engine.AddToken(history(
+		trade(pnlmodel.EventBuy, 100, 1),
+		trade(pnlmodel.EventSell, 30, 0.6),
+		trade(pnlmodel.EventSell, 30, 0.9),
+		trade(pnlmodel.EventSell, 60, 1.2),
+	))
+
+	matches := engine.Result().TradeHistory[0].LotMatches
+
+	if len(matches) != 3 {
+		t.Fatalf("got %d lot matches, want 3: %+v", len(matches), matches)
+	}
+
+	// The last sell only finds 40 of its 60 tokens
+	for i, quantity := range []float64{30, 30, 40} {
+		if matches[i].BuyTxHash != "tx0" {
+			t.Errorf("match %d is against %s, want tx0", i, matches[i].BuyTxHash)
+		}
+
+		assertFloat(t, "Quantity", matches[i].Quantity, quantity)
+		assertFloat(t, "Cost", matches[i].Cost, quantity*0.01)
+	}
+
+	assertFloat(t, "TotalTokenSell", entry.TotalTokenSell, 100)
+	assertFloat(t, "TotalTokenSellActual", entry.TotalTokenSellActual, 120)
+	assertFloat(t, "UnexplainedTokenAmount", entry.UnexplainedTokenAmount, 20)
+	assertFloat(t, "UnexplainedRate", entry.UnexplainedRate, 20.0/120*100)
+	assertFloat(t, "TokenHoldAmount", entry.TokenHoldAmount, 0)
+}
+
+func TestAddTokenGuardsXPNLWithoutBuyCost(t *testing.T) {
+	tests := []struct {
+		name    string
+		history pnlmodel.TradeHistory
+	}{
+		{
+			name: "buy without quote amount or price",
+			history: history(
+				pnlmodel.EventTrade{EventType: pnlmodel.EventBuy, TokensAmount: 100},
+				trade(pnlmodel.EventSell, 100, 2),
+			),
+		},
+		{
+			name: "sells only",
+			history: history(
+				trade(pnlmodel.EventSell, 100, 2),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine("sol", "wallet", "SOL", Options{})
+			entry := engine.AddToken(tt.history)
+
+			for _, f := range []struct {
+				name  string
+				value float64
+			}{
+				{"XPNL", entry.XPNL},
+				{"XPNLRate", entry.XPNLRate},
+				{"XPNLTrade", entry.XPNLTrade},
+				{"XPNLRateTrade", entry.XPNLRateTrade},
+			} {
+				if f.value != 0 {
+					t.Errorf("%s = %v, want 0", f.name, f.value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package pnl
+
+import (
+	"fmt"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"sort"
+)
+
+// MergeHistory adds the trades of fresh, fetched since the watermark of
+// stored, to the stored history of the same token. Trades found in both are
+// kept once. Lot matches are dropped, AddToken computes them again from the
+// merged trades.
+func MergeHistory(stored pnlmodel.TradeHistory, fresh pnlmodel.TradeHistory) pnlmodel.TradeHistory {
+	merged := stored
+	merged.LotMatches = nil
+	merged.EventTrades = make([]pnlmodel.EventTrade, 0, len(stored.EventTrades)+len(fresh.EventTrades))
+
+	seen := make(map[string]bool)
+
+	for _, eventTrades := range [][]pnlmodel.EventTrade{stored.EventTrades, fresh.EventTrades} {
+		for _, eventTrade := range eventTrades {
+			key := tradeKey(eventTrade)
+
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			merged.EventTrades = append(merged.EventTrades, eventTrade)
+		}
+	}
+
+	sort.SliceStable(merged.EventTrades, func(i, j int) bool {
+		return merged.EventTrades[i].Timestamp < merged.EventTrades[j].Timestamp
+	})
+
+	if fresh.TokenSymbol != "" {
+		merged.TokenSymbol = fresh.TokenSymbol
+	}
+
+	// A history without new trades keeps the prices it was last valued at
+	if fresh.MarkPriceQuote != 0 || fresh.MarkPriceUSD != 0 {
+		merged.MarkPriceQuote = fresh.MarkPriceQuote
+		merged.MarkPriceUSD = fresh.MarkPriceUSD
+	}
+
+	merged.Truncated = stored.Truncated || fresh.Truncated
+
+	if fresh.Watermark > merged.Watermark {
+		merged.Watermark = fresh.Watermark
+	}
+
+	return merged
+}
+
+// tradeKey identifies a trade across two fetches of the same history. Not
+// every stored or fetched trade carries its transaction hash, so the key is
+// made of the fields all of them have.
+func tradeKey(eventTrade pnlmodel.EventTrade) string {
+	return fmt.Sprintf("%d/%s/%v", eventTrade.Timestamp, eventTrade.EventType, eventTrade.TokensAmount)
+}
@@ -0,0 +1,441 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/chains"
+	"pnl-scan-tool/platform/database/mongodb"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+	"pnl-scan-tool/src/pnl"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultOptions are the engine options used by scans that are not given
+// their own, such as batch rescans and top holder scans.
+var DefaultOptions pnl.Options
+
+// CountStableRotations scans stablecoins like any other token, so that
+// rotations between them are counted rather than dropped.
+var CountStableRotations bool
+
+// DeepPNLScan scans a wallet through the configured Source and returns its
+// chain-neutral PNL. When ctx is done before every token is scanned, the
+// partial PNL is saved marked as incomplete and returned with ctx's error.
+func DeepPNLScan(ctx context.Context, chainID string, walletAddress string, scanDay int, options pnl.Options) (*pnlmodel.PNL, error) {
+	chain, err := chains.Get(chainID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	collection := chain.DayCollection()
+
+	if scanDay == 0 {
+		collection = chain.AllTimeCollection()
+	}
+
+	return scanWallet(ctx, Source, chain, walletAddress, scanDay, options, collection, nil)
+}
+
+// IncrementalPNLScan rescans a wallet whose all-time PNL is stored. Only the
+// trades made since each token's watermark are fetched and merged into the
+// stored histories, from which the PNL is computed again.
+func IncrementalPNLScan(ctx context.Context, chainID string, stored *pnlmodel.PNL, options pnl.Options) (*pnlmodel.PNL, error) {
+	chain, err := chains.Get(chainID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanWallet(ctx, Source, chain, stored.WalletAddress, 0, options, chain.AllTimeCollection(), newPreviousScan(stored))
+}
+
+// previousScan is what an incremental rescan reuses of a stored PNL.
+type previousScan struct {
+	histories map[string]pnlmodel.TradeHistory
+	// held lists the tokens the wallet still held, whose mark price is
+	// refreshed even without new trades.
+	held map[string]bool
+}
+
+func newPreviousScan(stored *pnlmodel.PNL) *previousScan {
+	previous := &previousScan{
+		histories: make(map[string]pnlmodel.TradeHistory),
+		held:      make(map[string]bool),
+	}
+
+	for _, history := range stored.TradeHistory {
+		previous.histories[history.TokenAddress] = history
+	}
+
+	for _, entry := range stored.XPNLs {
+		previous.held[entry.TokenAddress] = entry.TokenHoldAmount > 0
+	}
+
+	for _, entry := range stored.LostXPNLs {
+		previous.held[entry.TokenAddress] = entry.TokenHoldAmount > 0
+	}
+
+	return previous
+}
+
+// scanWallet computes the PNL of a wallet from src and saves it to collection.
+// previous, when set, is the stored scan the new trades are merged into.
+func scanWallet(ctx context.Context, src source.TradeSource, chain chains.Chain, walletAddress string, scanDay int, options pnl.Options, collection string, previous *previousScan) (*pnlmodel.PNL, error) {
+	walletAddress = chain.NormalizeAddress(walletAddress)
+
+	if options.InheritCost == nil {
+		options.InheritCost = storedHoldingCost(ctx, chain.AllTimeCollection())
+	}
+
+	if options.NativePriceUSD == 0 {
+		price, err := nativePriceUSD(ctx, chain.ID)
+
+		if err != nil {
+			fmt.Println("Error: failed to price the native coin: " + err.Error())
+		}
+
+		options.NativePriceUSD = price
+	}
+
+	engine := pnl.NewEngine(chain.ID, walletAddress, chain.QuoteAsset, options)
+
+	tokens, err := src.TradedTokens(ctx, chain.ID, walletAddress, scanDay)
+
+	if err != nil {
+		fmt.Println("Error: " + err.Error())
+		return nil, err
+	}
+
+	totalToken := len(tokens)
+
+	fmt.Println("Scan Total:", strconv.Itoa(totalToken)+" Token")
+
+	count := 0
+
+	fmt.Println("========================================================================================")
+	fmt.Println("")
+
+	for _, token := range tokens {
+		if ctx.Err() != nil {
+			break
+		}
+
+		count++
+
+		fmt.Println("Scanning Token Address: " + token.Address)
+
+		if exclusion, excluded := chain.Exclusion(token.Address); excluded && !(CountStableRotations && exclusion.Category == chains.Stablecoin) {
+			fmt.Printf("Excluded: %s (%s)\n", exclusion.Symbol, exclusion.Category)
+			fmt.Println("========================================================================================")
+
+			symbol := exclusion.Symbol
+			if symbol == "" {
+				symbol = token.Symbol
+			}
+
+			engine.Exclude(token.Address, symbol, string(exclusion.Category))
+			continue
+		}
+
+		stored, merge := pnlmodel.TradeHistory{}, false
+
+		if previous != nil {
+			stored, merge = previous.histories[token.Address]
+		}
+
+		var tradeHistory pnlmodel.TradeHistory
+
+		if incremental, ok := src.(source.IncrementalSource); ok && merge && stored.Watermark != 0 {
+			tradeHistory, err = incremental.TradesSince(ctx, chain.ID, walletAddress, token.Address, stored.Watermark)
+		} else {
+			tradeHistory, err = src.Trades(ctx, chain.ID, walletAddress, token.Address)
+		}
+
+		if err != nil {
+			fmt.Println("Error: " + err.Error())
+			fmt.Println("========================================================================================")
+
+			// Cancelled, the partial result is saved below
+			if ctx.Err() != nil {
+				break
+			}
+
+			// The remaining tokens would fail the same way
+			if isFatal(err) {
+				return nil, err
+			}
+
+			// Keep what the last scan knew rather than dropping the token
+			if !merge {
+				continue
+			}
+
+			tradeHistory = pnlmodel.TradeHistory{TokenAddress: token.Address}
+		}
+
+		if tradeHistory.Watermark == 0 && len(tradeHistory.EventTrades) != 0 {
+			tradeHistory.Watermark = tradeHistory.EventTrades[len(tradeHistory.EventTrades)-1].Timestamp
+		}
+
+		// Sources read from the chain carry no prices
+		needsPrice := tradeHistory.MarkPriceQuote == 0 && tradeHistory.MarkPriceUSD == 0
+
+		if merge {
+			tradeHistory = pnl.MergeHistory(stored, tradeHistory)
+
+			fmt.Printf("Merged %d new trade(s) into %d stored\n", len(tradeHistory.EventTrades)-len(stored.EventTrades), len(stored.EventTrades))
+
+			// A token sold out keeps its last valuation, one still held is
+			// priced again
+			needsPrice = needsPrice && previous.held[token.Address]
+		}
+
+		if tradeHistory.TokenSymbol == "" {
+			tradeHistory.TokenSymbol = token.Symbol
+		}
+
+		if needsPrice {
+			if price, err := src.Price(ctx, chain.ID, token.Address); err == nil {
+				tradeHistory.MarkPriceQuote = price.PriceQuote
+				tradeHistory.MarkPriceUSD = price.PriceUSD
+
+				if tradeHistory.TokenSymbol == "" {
+					tradeHistory.TokenSymbol = price.Symbol
+				}
+			}
+		}
+
+		fmt.Println("Token Symbol: " + tradeHistory.TokenSymbol)
+
+		if tradeHistory.Truncated {
+			fmt.Println("Warning: trade history is incomplete, the source cap was reached")
+		}
+
+		fmt.Println("----------------------")
+
+		for _, eventTrade := range tradeHistory.EventTrades {
+			fmt.Printf("%#v\n", eventTrade)
+			fmt.Println("----------------------")
+		}
+
+		entry := engine.AddToken(tradeHistory)
+
+		if entry == nil {
+			fmt.Println("========================================================================================")
+			continue
+		}
+
+		printTokenProgress(engine, entry, count, totalToken)
+	}
+
+	pnlHistory := engine.Result()
+	pnlHistory.ScanDay = scanDay
+	pnlHistory.Incomplete = ctx.Err() != nil
+
+	printPNLSummary(pnlHistory)
+
+	// The partial result is still saved when the scan was cancelled
+	if err := savePNL(context.WithoutCancel(ctx), collection, pnlHistory); err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+
+	return pnlHistory, ctx.Err()
+}
+
+// windowScanned reports whether a windowed scan of wallet is stored, which
+// the batch scans skip the wallet for.
+func windowScanned(ctx context.Context, chain chains.Chain, wallet string) bool {
+	_, err := mongodb.FindOne(ctx, chain.DayCollection(), bson.M{"walletaddress": chain.NormalizeAddress(wallet)})
+
+	if err == nil {
+		fmt.Println("Wallet Scan PNL already exists in the database.")
+	}
+
+	return err == nil
+}
+
+// storedHoldingCost resolves the cost a sending wallet holds a token at from
+// its last scan stored in collection.
+func storedHoldingCost(ctx context.Context, collection string) pnl.CostResolver {
+	return func(sender string, token string) (float64, float64, bool) {
+		document, err := mongodb.FindOne(ctx, collection, bson.M{"walletaddress": sender})
+
+		if err != nil || legacyPNL(document) {
+			return 0, 0, false
+		}
+
+		stored, err := decodePNL(document)
+
+		if err != nil {
+			return 0, 0, false
+		}
+
+		entries := stored.XPNLs
+
+		for _, lost := range stored.LostXPNLs {
+			entries = append(entries, pnlmodel.XPNL(lost))
+		}
+
+		for _, entry := range entries {
+			if entry.TokenAddress == token && entry.TokenHoldAmount > 0 {
+				return entry.TokenHoldCostQuote / entry.TokenHoldAmount, entry.TokenHoldCostUSD / entry.TokenHoldAmount, true
+			}
+		}
+
+		return 0, 0, false
+	}
+}
+
+// legacyPNL reports whether document was saved by the per-chain scans, whose
+// trades are stored under other field names (pricesol, solamount, ...) and
+// decode with every quote figure at 0.
+func legacyPNL(document bson.M) bool {
+	_, hasQuote := document["quoteasset"]
+	_, hasHistory := document["tradehistory"]
+
+	return !hasQuote || !hasHistory
+}
+
+// decodePNL decodes a stored scan result.
+func decodePNL(document bson.M) (*pnlmodel.PNL, error) {
+	raw, err := bson.Marshal(document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stored pnlmodel.PNL
+
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+
+	return &stored, nil
+}
+
+// savePNL upserts the scan result of a wallet.
+func savePNL(ctx context.Context, collection string, pnlHistory *pnlmodel.PNL) error {
+	filter := bson.M{"walletaddress": pnlHistory.WalletAddress}
+
+	update := bson.M{"$set": bson.M{
+		"chain":          pnlHistory.Chain,
+		"quoteasset":     pnlHistory.QuoteAsset,
+		"costbasis":      pnlHistory.CostBasis,
+		"scanday":        pnlHistory.ScanDay,
+		"tradehistory":   pnlHistory.TradeHistory,
+		"xpnls":          pnlHistory.XPNLs,
+		"lostxpnls":      pnlHistory.LostXPNLs,
+		"excludedtokens": pnlHistory.Excluded,
+		"incomplete":     pnlHistory.Incomplete,
+		"summaryreview":  pnlHistory.SummaryReview,
+	}}
+
+	_, err := mongodb.FindAndUpdateWithRollback(ctx, collection, filter, update)
+
+	return err
+}
+
+func printTokenProgress(engine *pnl.Engine, entry *pnlmodel.XPNL, count int, totalToken int) {
+	quote := engine.QuoteAsset()
+	summary := engine.Summary()
+
+	fmt.Println("************************************************************************************")
+	fmt.Println("")
+
+	fmt.Printf("Profit %s: %v\n", quote, entry.ProfitQuote)
+	fmt.Printf("Profit %s Actual: %v\n", quote, entry.ProfitQuoteActual)
+
+	fmt.Println("")
+
+	fmt.Printf("Scan Progress: %.2f %%\n", (float64(count)/float64(totalToken))*100.0)
+	fmt.Printf("Total Scan: %d/%d\n", count, totalToken)
+
+	fmt.Println("")
+
+	fmt.Printf("PNL %s: %v\n", quote, summary.TotalPNLAmount)
+	fmt.Printf("PNL %s Actual: %v\n", quote, summary.TotalPNLAmountActual)
+
+	fmt.Println("")
+
+	fmt.Println("************************************************************************************")
+
+	fmt.Println("")
+
+	fmt.Println("========================================================================================")
+
+	fmt.Println("")
+}
+
+func printPNLSummary(pnlHistory *pnlmodel.PNL) {
+	quote := pnlHistory.QuoteAsset
+
+	fmt.Println("+++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++")
+
+	fmt.Println("")
+	fmt.Println("WIN ..............................................")
+	fmt.Println("")
+
+	for _, xpnl := range pnlHistory.XPNLs {
+		printXPNL(quote, xpnl)
+	}
+
+	fmt.Println("")
+	fmt.Println("LOST ..............................................")
+	fmt.Println("")
+
+	for _, xpnl := range pnlHistory.LostXPNLs {
+		printXPNL(quote, pnlmodel.XPNL(xpnl))
+	}
+
+	if len(pnlHistory.Excluded) != 0 {
+		fmt.Println("")
+		fmt.Println("EXCLUDED ..............................................")
+		fmt.Println("")
+
+		for _, excluded := range pnlHistory.Excluded {
+			fmt.Printf("%s - %s (%s)\n", excluded.TokenSymbol, excluded.TokenAddress, excluded.Category)
+		}
+	}
+
+	summary := pnlHistory.SummaryReview
+
+	fmt.Println("")
+	fmt.Println("Cost Basis: ", pnlHistory.CostBasis)
+
+	if pnlHistory.Incomplete {
+		fmt.Println("Incomplete: the scan was cancelled before every token was scanned")
+	}
+
+	fmt.Printf("Big XPNL: %d/%d \n", summary.BigXPNL, len(pnlHistory.XPNLs)+len(pnlHistory.LostXPNLs))
+	fmt.Printf("Rate Big XPNL: %.2f %%\n", summary.RateBigXPNL)
+	fmt.Println("Total Win: ", summary.TotalWin)
+	fmt.Println("Total Lost: ", summary.TotalLost)
+	fmt.Printf("Win Rate: %2.f %%\n", summary.WinRate)
+	fmt.Printf("Total PNL %s: %v\n", quote, summary.TotalPNLAmount)
+	fmt.Printf("Total PNL %s Actual: %v\n", quote, summary.TotalPNLAmountActual)
+	fmt.Printf("Total Fees: %.4[2]f %[1]s | Total PNL %[1]s Net: %[3]v\n", quote, summary.TotalFees, summary.TotalPNLAmountNet)
+	fmt.Printf("Total PNL USD: %.2f $ | ROI USD: %.2f %%\n", summary.TotalPNLAmountUSD, summary.ROIUSD)
+	fmt.Printf("Win Rate USD: %2.f %%\n", summary.WinRateUSD)
+	if summary.TotalTruncated != 0 {
+		fmt.Printf("Incomplete History: %d token(s)\n", summary.TotalTruncated)
+	}
+	fmt.Println("+++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++")
+}
+
+func printXPNL(quote string, xpnl pnlmodel.XPNL) {
+	fmt.Printf("%s - %.2fx | Trade:%2.fx\n Total Buy: %d | Total Sell: %d | StartTime: %s | EndTime: %s\n", xpnl.TokenSymbol, xpnl.XPNL, xpnl.XPNLTrade, xpnl.CountBuy, xpnl.CountSell, xpnl.StartTime, xpnl.EndTime)
+	fmt.Printf(" Total %[1]s Buy: %.2[2]f %[1]s  | Total %[1]s Sell: %.2[3]f %[1]s | Total %[1]s Sell Actual: %.2[4]f %[1]s\n", quote, xpnl.TotalQuoteBuy, xpnl.TotalQuoteSell, xpnl.TotalQuoteSellActual)
+	fmt.Printf(" Profit %[1]s: %.2[2]f %[1]s | Profit %[1]s Actual: %.2[3]f %[1]s\n", quote, xpnl.ProfitQuote, xpnl.ProfitQuoteActual)
+	fmt.Printf(" Realized: %.2[2]f %[1]s | Unrealized: %.2[3]f %[1]s\n", quote, xpnl.RealizedProfitQuote, xpnl.UnrealizedProfitQuote)
+	fmt.Printf(" Fees: %.4[2]f %[1]s | Profit %[1]s Net: %.2[3]f %[1]s\n", quote, xpnl.TotalFees, xpnl.ProfitQuoteNet)
+	fmt.Printf(" Profit USD: %.2f $ | Realized USD: %.2f $ | Unrealized USD: %.2f $ | ROI USD: %.2f %%\n", xpnl.ProfitUSD, xpnl.RealizedProfitUSD, xpnl.UnrealizedProfitUSD, xpnl.ROIUSD)
+	if xpnl.TotalTokenInflow != 0 || xpnl.TotalTokenOutflow != 0 || xpnl.UnexplainedTokenAmount != 0 {
+		fmt.Printf(" Inflow: %.2f (cost %.2[3]f %[2]s) | Outflow: %.2[4]f | Unexplained: %.2[5]f (%.2[6]f %%)\n", xpnl.TotalTokenInflow, quote, xpnl.TotalQuoteInflowCost, xpnl.TotalTokenOutflow, xpnl.UnexplainedTokenAmount, xpnl.UnexplainedRate)
+	}
+	fmt.Printf(" xPNL Rate: %.2f %% | xPNL Rate Trade: %.2f %%\n", xpnl.XPNLRate, xpnl.XPNLRateTrade)
+}
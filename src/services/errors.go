@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pnl-scan-tool/core/source"
+)
+
+// isFatal reports whether err will fail every request that follows, so that
+// a batch should stop instead of moving on to the next wallet.
+func isFatal(err error) bool {
+	return errors.Is(err, source.ErrAuthExpired) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// WalletError is the failure of one wallet of a batch scan.
+type WalletError struct {
+	Wallet string
+	Err    error
+}
+
+// BatchErrors collects the wallets a batch scan failed on.
+type BatchErrors []WalletError
+
+// Add records the failure of wallet.
+func (b *BatchErrors) Add(wallet string, err error) {
+	*b = append(*b, WalletError{Wallet: wallet, Err: err})
+}
+
+// Print prints how many wallets failed per error kind, then every failure.
+func (b BatchErrors) Print(total int) {
+	fmt.Println("+++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++")
+	fmt.Printf("Scanned: %d/%d wallet(s) | Failed: %d\n", total-len(b), total, len(b))
+
+	if len(b) == 0 {
+		fmt.Println("+++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++")
+		return
+	}
+
+	kinds := make(map[string]int)
+	var order []string
+
+	for _, failure := range b {
+		kind := source.Kind(failure.Err)
+
+		if kinds[kind] == 0 {
+			order = append(order, kind)
+		}
+
+		kinds[kind]++
+	}
+
+	for _, kind := range order {
+		fmt.Printf(" %s: %d\n", kind, kinds[kind])
+	}
+
+	fmt.Println("")
+
+	for _, failure := range b {
+		fmt.Printf("%s [%s] %v\n", failure.Wallet, source.Kind(failure.Err), failure.Err)
+	}
+
+	fmt.Println("+++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++")
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pnl-scan-tool/package/chains"
+	"pnl-scan-tool/package/workerpool"
+	"pnl-scan-tool/platform/database/mongodb"
+	jobmodel "pnl-scan-tool/src/model/job.model"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const jobsCollection = "scan_jobs"
+
+// ErrJobNotFound is returned for an unknown job or queued scan.
+var ErrJobNotFound = errors.New("job not found")
+
+// maxItemAttempts is how many times a wallet is scanned, over every resume of
+// its job, before it is left failed.
+const maxItemAttempts = 3
+
+// ScanConcurrency is how many wallets of a batch are scanned at once. The
+// requests of all of them share the rate limits of each provider.
+var ScanConcurrency = 1
+
+// ResumeJob runs the wallets of a stored job that are not done yet.
+func ResumeJob(ctx context.Context, jobID string) {
+	job, err := loadJob(ctx, jobID)
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if job.Status == jobmodel.StatusDone {
+		fmt.Println("Job", job.ID, "is already done.")
+		return
+	}
+
+	chain, err := chains.Get(job.Chain)
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	done := 0
+
+	for _, item := range job.Items {
+		if item.Status == jobmodel.StatusDone {
+			done++
+		}
+	}
+
+	fmt.Printf("Resuming job %s: %s on %s, %d/%d wallet(s) done\n", job.ID, job.Kind, job.Chain, done, len(job.Items))
+
+	switch job.Kind {
+	case jobmodel.KindTopTraders:
+		runTopTraders(ctx, chain, job)
+	case jobmodel.KindTopHolders:
+		runTopHolders(ctx, chain, job)
+	case jobmodel.KindRescan:
+		runRescan(ctx, chain, job)
+	default:
+		fmt.Println("Error: unknown job kind:", job.Kind)
+	}
+}
+
+// createJob stores job with one pending item per wallet, skipping repeated
+// wallets.
+func createJob(ctx context.Context, job *jobmodel.Job, wallets []string) error {
+	now := time.Now().Unix()
+
+	job.ID = primitive.NewObjectID().Hex()
+	job.Status = jobmodel.StatusRunning
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	seen := make(map[string]bool, len(wallets))
+
+	for _, wallet := range wallets {
+		if seen[wallet] {
+			continue
+		}
+
+		seen[wallet] = true
+		job.Items = append(job.Items, jobmodel.JobItem{Wallet: wallet, Status: jobmodel.StatusPending})
+	}
+
+	if _, err := mongodb.InsertDocumentWithRollback(ctx, jobsCollection, job); err != nil {
+		return err
+	}
+
+	fmt.Printf("Job: %s (resume with --resume %s)\n", job.ID, job.ID)
+
+	return nil
+}
+
+func loadJob(ctx context.Context, jobID string) (*jobmodel.Job, error) {
+	documents, err := mongodb.FindDocuments(ctx, jobsCollection, bson.M{"_id": jobID}, 1, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", jobID, err)
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+
+	raw, err := bson.Marshal(documents[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	var job jobmodel.Job
+
+	if err := bson.Unmarshal(raw, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// updateJob saves fields of job. It still saves when ctx is done, so that a
+// cancelled job keeps its checkpoint.
+func updateJob(ctx context.Context, job *jobmodel.Job, fields bson.M) {
+	job.UpdatedAt = time.Now().Unix()
+	fields["updatedat"] = job.UpdatedAt
+
+	_, err := mongodb.FindAndUpdateWithRollback(context.WithoutCancel(ctx), jobsCollection, bson.M{"_id": job.ID}, bson.M{"$set": fields})
+
+	if err != nil {
+		fmt.Println("Error saving job checkpoint:", err)
+	}
+}
+
+// saveItem saves item i of job jobID. Like updateJob it still saves when ctx
+// is done. It only reads its arguments, so it runs outside the lock of runJob.
+func saveItem(ctx context.Context, jobID string, i int, item jobmodel.JobItem) {
+	_, err := mongodb.FindAndUpdateWithRollback(context.WithoutCancel(ctx), jobsCollection, bson.M{"_id": jobID}, bson.M{"$set": bson.M{
+		fmt.Sprintf("items.%d", i): item,
+		"updatedat":                time.Now().Unix(),
+	}})
+
+	if err != nil {
+		fmt.Println("Error saving job checkpoint:", err)
+	}
+}
+
+// runJob scans every wallet of job that is not done yet on a worker pool of
+// ScanConcurrency workers, saving the status of each one as it goes. It stops
+// early when ctx is done or a wallet fails in a way the next ones would too,
+// and returns that error.
+func runJob(ctx context.Context, job *jobmodel.Job, scan func(ctx context.Context, wallet string) error) (BatchErrors, error) {
+	var failures BatchErrors
+	var stopErr error
+	var mu sync.Mutex
+
+	// A fatal error cancels the scans still running or queued
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	concurrency := max(ScanConcurrency, 1)
+
+	pool := workerpool.NewWorkerPool(runCtx, concurrency, concurrency, time.Minute)
+	pool.Run()
+
+	var pending []int
+
+	for i := range job.Items {
+		item := &job.Items[i]
+
+		if item.Status == jobmodel.StatusDone {
+			continue
+		}
+
+		if item.Status == jobmodel.StatusFailed && item.Attempts >= maxItemAttempts {
+			failures.Add(item.Wallet, errors.New(item.LastError))
+			continue
+		}
+
+		pending = append(pending, i)
+	}
+
+	completed := 0
+
+	for _, i := range pending {
+		i := i
+
+		// Cancelling one wallet leaves the others running, stop cancels them
+		// all as the parent of every item
+		itemCtx, cancelItem := context.WithCancel(runCtx)
+
+		err := pool.AddTask(&workerpool.Task{
+			ID: job.Items[i].Wallet,
+			Job: func(taskCtx context.Context) error {
+				mu.Lock()
+				item := &job.Items[i]
+				item.Status = jobmodel.StatusRunning
+				item.Attempts++
+				wallet, saved := item.Wallet, *item
+				mu.Unlock()
+
+				saveItem(ctx, job.ID, i, saved)
+
+				err := scan(taskCtx, wallet)
+
+				mu.Lock()
+
+				switch {
+				case err == nil:
+					item.Status = jobmodel.StatusDone
+					item.LastError = ""
+				case isFatal(err) || runCtx.Err() != nil:
+					// Not the wallet's fault, it is scanned again on resume
+					item.Status = jobmodel.StatusPending
+					item.Attempts--
+					item.LastError = err.Error()
+
+					if stopErr == nil {
+						failures.Add(wallet, err)
+						stopErr = err
+						stop()
+					}
+				default:
+					item.Status = jobmodel.StatusFailed
+					item.LastError = err.Error()
+					failures.Add(wallet, err)
+				}
+
+				saved = *item
+				completed++
+				progress := fmt.Sprintf("Progress: %d/%d wallet(s) | %s | Failed: %d", completed, len(pending), wallet, len(failures))
+				mu.Unlock()
+
+				saveItem(ctx, job.ID, i, saved)
+				fmt.Println(progress)
+
+				return err
+			},
+			Ctx:        itemCtx,
+			CancelFunc: cancelItem,
+			Priority:   1,
+			CreatedAt:  time.Now(),
+		})
+
+		if err != nil {
+			cancelItem()
+		}
+
+		// Once runCtx is done the wallets left stay pending
+		if err != nil && runCtx.Err() == nil {
+			// Left pending the wallet would hold the job back from done
+			fmt.Println("Error:", err)
+
+			mu.Lock()
+			item := &job.Items[i]
+			item.Status = jobmodel.StatusFailed
+			item.LastError = err.Error()
+			failures.Add(item.Wallet, err)
+			saved := *item
+			mu.Unlock()
+
+			saveItem(ctx, job.ID, i, saved)
+		}
+	}
+
+	pool.Wait()
+	pool.Shutdown()
+
+	// Wallets left queued when ctx was done stay pending
+	if stopErr == nil {
+		stopErr = ctx.Err()
+	}
+
+	job.Status = jobmodel.StatusDone
+
+	switch {
+	case errors.Is(stopErr, context.Canceled), errors.Is(stopErr, context.DeadlineExceeded):
+		job.Status = jobmodel.StatusCancelled
+	case stopErr != nil:
+		job.Status = jobmodel.StatusFailed
+	}
+
+	updateJob(ctx, job, bson.M{"status": job.Status})
+
+	if stopErr != nil {
+		fmt.Printf("Job %s stopped: %v (resume with --resume %s)\n", job.ID, stopErr, job.ID)
+	}
+
+	return failures, stopErr
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pnl-scan-tool/package/chains"
+	"pnl-scan-tool/platform/database/mongodb"
+	"pnl-scan-tool/platform/queue"
+	jobmodel "pnl-scan-tool/src/model/job.model"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrPNLNotFound is returned for a wallet without a stored scan.
+var ErrPNLNotFound = errors.New("no scan stored for wallet")
+
+// ErrLegacyPNL is returned for a wallet whose stored scan predates the
+// chain-neutral schema and must be scanned again to be read.
+var ErrLegacyPNL = errors.New("stored scan predates the chain-neutral schema, rescan the wallet")
+
+// PNLScanRequest is the body of a scan request.
+type PNLScanRequest struct {
+	// ScanDay is the window of the scan in days, 0 for all time.
+	ScanDay int `json:"scanDay" example:"30"`
+}
+
+// PNLScanResponse represents the response for a queued scan
+type PNLScanResponse struct {
+	Message string `json:"message"`
+	JobID   string `json:"jobId"`
+	Chain   string `json:"chain"`
+	Wallet  string `json:"wallet"`
+	ScanDay int    `json:"scanDay"`
+}
+
+// JobStatusResponse represents the status of a queued scan or of a batch job
+type JobStatusResponse struct {
+	ID string `json:"id"`
+	// Kind is deepscan for a queued scan, toptraders, topholders or rescan
+	// for a batch job.
+	Kind string `json:"kind" example:"deepscan"`
+	// Status is pending, running, done, failed or cancelled.
+	Status    string `json:"status" example:"pending"`
+	Chain     string `json:"chain"`
+	Wallet    string `json:"wallet,omitempty"`
+	Token     string `json:"token,omitempty"`
+	ScanDay   int    `json:"scanDay"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// Done, Failed and Total count the wallets of a batch job.
+	Done      int   `json:"done"`
+	Failed    int   `json:"failed"`
+	Total     int   `json:"total"`
+	CreatedAt int64 `json:"createdAt"`
+	UpdatedAt int64 `json:"updatedAt"`
+}
+
+// GetPNL returns the stored scan of wallet: the all-time one when scanDay is 0,
+// the latest windowed one otherwise, whatever window it covers. Only one
+// windowed scan is kept per wallet, its ScanDay tells which window it holds.
+func GetPNL(ctx context.Context, chainID string, wallet string, scanDay int) (*pnlmodel.PNL, error) {
+	chain, err := chains.Get(chainID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	collection := chain.DayCollection()
+
+	if scanDay == 0 {
+		collection = chain.AllTimeCollection()
+	}
+
+	documents, err := mongodb.FindDocuments(ctx, collection, bson.M{"walletaddress": chain.NormalizeAddress(wallet)}, 1, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPNLNotFound, wallet)
+	}
+
+	if legacyPNL(documents[0]) {
+		return nil, fmt.Errorf("%w: %s", ErrLegacyPNL, wallet)
+	}
+
+	return decodePNL(documents[0])
+}
+
+// JobStatus returns the status of the queued scan or batch job id, or
+// ErrJobNotFound.
+func JobStatus(ctx context.Context, id string) (*JobStatusResponse, error) {
+	m, err := ScanQueue.Get(ctx, id)
+
+	if err == nil {
+		return scanStatus(m)
+	}
+
+	if !errors.Is(err, queue.ErrNotFound) {
+		return nil, err
+	}
+
+	job, err := loadJob(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := &JobStatusResponse{
+		ID:        job.ID,
+		Kind:      job.Kind,
+		Status:    job.Status,
+		Chain:     job.Chain,
+		Token:     job.Token,
+		Failed:    failedItems(job),
+		Total:     len(job.Items),
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+
+	for _, item := range job.Items {
+		if item.Status == jobmodel.StatusDone {
+			status.Done++
+		}
+	}
+
+	return status, nil
+}
+
+func scanStatus(m *queue.Message) (*JobStatusResponse, error) {
+	var request ScanRequest
+
+	if err := m.Decode(&request); err != nil {
+		return nil, err
+	}
+
+	status := &JobStatusResponse{
+		ID:        m.ID,
+		Kind:      ScanQueue.Name,
+		Status:    m.Status,
+		Chain:     request.Chain,
+		Wallet:    request.Wallet,
+		ScanDay:   request.ScanDay,
+		Attempts:  m.Attempts,
+		LastError: m.LastError,
+		Total:     1,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+
+	switch m.Status {
+	case queue.StatusLeased:
+		status.Status = jobmodel.StatusRunning
+	case queue.StatusDone:
+		status.Done = 1
+	case queue.StatusDead:
+		status.Status = jobmodel.StatusFailed
+		status.Failed = 1
+	}
+
+	return status, nil
+}
+
+// EnqueuePNLScanHandler queues a deep scan of a wallet
+// @Summary Queue a wallet PnL scan
+// @Description Queues a deep scan of the wallet over its last scanDay days, 0 for all time. The scan runs on the scan queue consumers; its status is at /api/jobs/{id}
+// @Tags pnl
+// @Accept json
+// @Produce json
+// @Param chain path string true "Chain ID" example(sol)
+// @Param wallet path string true "Wallet address"
+// @Param scan body PNLScanRequest false "Scan window"
+// @Success 202 {object} PNLScanResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/pnl/{chain}/{wallet} [post]
+func EnqueuePNLScanHandler(c *fiber.Ctx) error {
+	var request PNLScanRequest
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error: "Invalid request body",
+			})
+		}
+	}
+
+	chain, err := chains.Get(c.Params("chain"))
+
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	wallet := c.Params("wallet")
+
+	if !chain.ValidAddress(wallet) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error: fmt.Sprintf("Invalid %s wallet address", chain.ID),
+		})
+	}
+
+	if request.ScanDay < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error: "scanDay must be 0 or more",
+		})
+	}
+
+	m, err := EnqueueScan(c.UserContext(), chain.ID, wallet, request.ScanDay)
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(PNLScanResponse{
+		Message: "Scan queued",
+		JobID:   m.ID,
+		Chain:   chain.ID,
+		Wallet:  chain.NormalizeAddress(wallet),
+		ScanDay: request.ScanDay,
+	})
+}
+
+// GetPNLHandler returns the stored PnL of a wallet
+// @Summary Get a wallet PnL
+// @Description Returns the stored scan of the wallet: the all-time one by default, the latest windowed one when scanDay is set. Only one windowed scan is kept per wallet, so scanDay selects the windowed scan rather than a window: any value above 0 returns it whatever window it covers, which is its scan-day. A scan stored before the chain-neutral schema is answered with 409 until the wallet is scanned again
+// @Tags pnl
+// @Produce json
+// @Param chain path string true "Chain ID" example(sol)
+// @Param wallet path string true "Wallet address"
+// @Param scanDay query int false "0 for the all-time scan, above 0 for the latest windowed scan"
+// @Success 200 {object} pnlmodel.PNL
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/pnl/{chain}/{wallet} [get]
+func GetPNLHandler(c *fiber.Ctx) error {
+	scanDay := c.QueryInt("scanDay", 0)
+
+	if scanDay < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error: "scanDay must be 0 or more",
+		})
+	}
+
+	if _, err := chains.Get(c.Params("chain")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	result, err := GetPNL(c.UserContext(), c.Params("chain"), c.Params("wallet"), scanDay)
+
+	if errors.Is(err, ErrPNLNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	if errors.Is(err, ErrLegacyPNL) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// GetJobHandler returns the status of a queued scan or batch job
+// @Summary Get a job status
+// @Description Returns the status of a scan queued through /api/pnl, or of a batch scan job
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} JobStatusResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/jobs/{id} [get]
+func GetJobHandler(c *fiber.Ctx) error {
+	status, err := JobStatus(c.UserContext(), c.Params("id"))
+
+	if errors.Is(err, ErrJobNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(status)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"pnl-scan-tool/package/chains"
+	"pnl-scan-tool/platform/queue"
+)
+
+const queueCollection = "scan_queue"
+
+// ScanQueue holds the wallet scans requested outside of a batch command. Every
+// scanner process consuming it shares the work.
+var ScanQueue = queue.New(queueCollection, "deepscan")
+
+// ScanRequest is the payload of a ScanQueue message.
+type ScanRequest struct {
+	Chain   string `json:"chain" bson:"chain"`
+	Wallet  string `json:"wallet" bson:"wallet"`
+	ScanDay int    `json:"scan-day" bson:"scanday"`
+}
+
+// EnqueueScan queues a deep scan of wallet over its last scanDay days, 0 for
+// all time.
+func EnqueueScan(ctx context.Context, chainID string, wallet string, scanDay int) (*queue.Message, error) {
+	chain, err := chains.Get(chainID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !chain.ValidAddress(wallet) {
+		return nil, fmt.Errorf("invalid %s wallet address: %q", chain.ID, wallet)
+	}
+
+	if scanDay < 0 {
+		return nil, fmt.Errorf("invalid scan day: %d", scanDay)
+	}
+
+	request := ScanRequest{Chain: chain.ID, Wallet: chain.NormalizeAddress(wallet), ScanDay: scanDay}
+
+	return ScanQueue.Enqueue(ctx, request, 0)
+}
+
+// ConsumeScans runs the queued scans, ScanConcurrency at a time, until ctx is
+// done. The scans still running then are handed back to the queue.
+func ConsumeScans(ctx context.Context) {
+	if err := ScanQueue.EnsureIndexes(ctx); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	fmt.Printf("Consuming %s scans as %s\n", ScanQueue.Name, ScanQueue.Owner)
+
+	ScanQueue.Consume(ctx, ScanConcurrency, func(ctx context.Context, m *queue.Message) error {
+		var request ScanRequest
+
+		if err := m.Decode(&request); err != nil {
+			return err
+		}
+
+		fmt.Printf("Scan: %s on %s, %d day(s) (message %s)\n", request.Wallet, request.Chain, request.ScanDay, m.ID)
+
+		_, err := DeepPNLScan(ctx, request.Chain, request.Wallet, request.ScanDay, DefaultOptions)
+
+		return err
+	})
+}
@@ -1,43 +1,125 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"pnl-scan-tool/package/chains"
 	"pnl-scan-tool/platform/database/mongodb"
+	jobmodel "pnl-scan-tool/src/model/job.model"
+	pnlmodel "pnl-scan-tool/src/model/pnl.model"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-func ReScanWalletPNLJob(chain string) {
-	var collection string
+// BackfillUSDFilter selects the stored wallets that were scanned before USD
+// figures were computed.
+var BackfillUSDFilter = bson.M{"summaryreview.totalpnlamountusd": bson.M{"$exists": false}}
 
-	if chain == "sol" {
-		collection = "all_time_pnl_wallet_sol"
-		filter := bson.M{}
+// ReScanWalletPNLJob rescans every all-time wallet of the chain matching filter.
+// An incremental rescan only fetches the trades made since the stored scan of
+// each wallet, a full one scans every wallet from scratch. It returns the job
+// it ran, and the error it stopped on.
+func ReScanWalletPNLJob(ctx context.Context, chainID string, filter bson.M, incremental bool) (*jobmodel.Job, error) {
+	chain, err := chains.Get(chainID)
 
-		pnlWalletTracker, err := mongodb.FindDocuments(collection, filter, 0, nil)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return nil, err
+	}
 
-		if err != nil {
-			fmt.Println(err)
-		}
+	pnlWalletTracker, err := mongodb.FindDocuments(ctx, chain.AllTimeCollection(), filter, 0, nil)
 
-		for _, wallet := range pnlWalletTracker {
-			fmt.Println("Scan:", wallet["walletaddress"])
-			DeepPNLScanSol(chain, wallet["walletaddress"].(string), 0)
-		}
-	} else if chain == "eth" {
-		collection := "all_time_pnl_wallet_eth"
-		filter := bson.M{}
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
 
-		pnlWalletTracker, err := mongodb.FindDocuments(collection, filter, 0, nil)
+	var wallets []string
+
+	for _, wallet := range pnlWalletTracker {
+		wallets = append(wallets, wallet["walletaddress"].(string))
+	}
+
+	mode := "incremental"
+
+	if !incremental {
+		mode = "full"
+	}
+
+	job := &jobmodel.Job{Kind: jobmodel.KindRescan, Chain: chain.ID, Mode: mode}
+
+	if err := createJob(ctx, job, wallets); err != nil {
+		fmt.Println("Error:", err)
+		return nil, err
+	}
+
+	return job, runRescan(ctx, chain, job)
+}
+
+// runRescan rescans the wallets of a job from their stored all-time scans.
+func runRescan(ctx context.Context, chain chains.Chain, job *jobmodel.Job) error {
+	incremental := job.Mode == "incremental"
+
+	failures, err := runJob(ctx, job, func(ctx context.Context, walletAddress string) error {
+		fmt.Println("Scan:", walletAddress)
+
+		document, err := mongodb.FindOne(ctx, chain.AllTimeCollection(), bson.M{"walletaddress": walletAddress})
 
 		if err != nil {
-			fmt.Println(err)
+			return err
 		}
 
-		for _, wallet := range pnlWalletTracker {
-			fmt.Println("Scan:", wallet["walletaddress"])
-			DeepPNLScanETH(chain, wallet["walletaddress"].(string), 0)
+		_, err = rescanWallet(ctx, chain.ID, document, incremental)
+
+		return err
+	})
+
+	failures.Print(len(job.Items))
+
+	return err
+}
+
+// rescanWallet scans a stored wallet again, from its stored scan when
+// incremental.
+func rescanWallet(ctx context.Context, chainID string, document bson.M, incremental bool) (*pnlmodel.PNL, error) {
+	walletAddress := document["walletaddress"].(string)
+
+	if !incremental {
+		return DeepPNLScan(ctx, chainID, walletAddress, 0, DefaultOptions)
+	}
+
+	if legacyPNL(document) {
+		fmt.Println("Stored scan predates the chain-neutral schema, rescanning from scratch")
+		return DeepPNLScan(ctx, chainID, walletAddress, 0, DefaultOptions)
+	}
+
+	stored, err := decodePNL(document)
+
+	if err != nil {
+		fmt.Println("Error decoding stored scan, rescanning from scratch:", err)
+		return DeepPNLScan(ctx, chainID, walletAddress, 0, DefaultOptions)
+	}
+
+	if !hasWatermarks(stored) {
+		fmt.Println("Stored scan has no watermarks, rescanning from scratch")
+		return DeepPNLScan(ctx, chainID, walletAddress, 0, DefaultOptions)
+	}
+
+	return IncrementalPNLScan(ctx, chainID, stored, DefaultOptions)
+}
+
+// hasWatermarks reports whether every stored history records where its last
+// fetch stopped, which an incremental rescan resumes from.
+func hasWatermarks(stored *pnlmodel.PNL) bool {
+	if len(stored.TradeHistory) == 0 {
+		return false
+	}
+
+	for _, history := range stored.TradeHistory {
+		if history.Watermark == 0 && len(history.EventTrades) != 0 {
+			return false
 		}
 	}
 
+	return true
 }
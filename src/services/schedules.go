@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"pnl-scan-tool/package/chains"
+	"pnl-scan-tool/package/scheduler"
+	"pnl-scan-tool/package/utils"
+	"pnl-scan-tool/platform/database/mongodb"
+	jobmodel "pnl-scan-tool/src/model/job.model"
+	schedulemodel "pnl-scan-tool/src/model/schedule.model"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const schedulesCollection = "scan_schedules"
+
+// scheduleLease is how long a schedule stays locked to the process running it
+// once that process stops renewing the lock, e.g. after a crash.
+const scheduleLease = 5 * time.Minute
+
+// SchedulerTick is how often the scheduler looks for due schedules.
+var SchedulerTick = 30 * time.Second
+
+// scheduleOwner identifies this process in the schedule locks.
+var scheduleOwner = func() string {
+	hostname, _ := os.Hostname()
+	return hostname + "-" + primitive.NewObjectID().Hex()
+}()
+
+// scheduleFile is the layout of the schedules file.
+type scheduleFile struct {
+	Schedules []schedulemodel.Schedule `json:"schedules"`
+}
+
+// LoadSchedules saves the schedules of the file at path to MongoDB, where the
+// scheduler reads them from. The fields set in the file win over the stored
+// ones. Run records, and schedules only stored in MongoDB, are kept.
+func LoadSchedules(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var file scheduleFile
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse schedules: %w", err)
+	}
+
+	for _, schedule := range file.Schedules {
+		if err := validateSchedule(schedule); err != nil {
+			return err
+		}
+
+		update := bson.M{
+			"$set": bson.M{
+				"spec":    schedule.Spec,
+				"action":  schedule.Action,
+				"chain":   schedule.Chain,
+				"enabled": schedule.Enabled,
+				"mode":    schedule.Mode,
+				"filter":  schedule.Filter,
+			},
+			"$setOnInsert": bson.M{"createdat": time.Now().Unix()},
+		}
+
+		if _, err := mongodb.FindAndUpdateWithRollback(ctx, schedulesCollection, bson.M{"_id": schedule.Name}, update); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Loaded %d schedule(s) from %s\n", len(file.Schedules), path)
+
+	return nil
+}
+
+func validateSchedule(schedule schedulemodel.Schedule) error {
+	if schedule.Name == "" {
+		return errors.New("schedule without a name")
+	}
+
+	if _, err := scheduler.Parse(schedule.Spec); err != nil {
+		return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+	}
+
+	if schedule.Action != schedulemodel.ActionRescan {
+		return fmt.Errorf("schedule %s: unknown action %q", schedule.Name, schedule.Action)
+	}
+
+	if _, err := chains.Get(schedule.Chain); err != nil {
+		return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+	}
+
+	switch schedule.Mode {
+	case "", "incremental", "full", "backfill-usd":
+		return nil
+	default:
+		return fmt.Errorf("schedule %s: unknown rescan mode %q", schedule.Name, schedule.Mode)
+	}
+}
+
+// ListSchedules returns every stored schedule.
+func ListSchedules(ctx context.Context) ([]schedulemodel.Schedule, error) {
+	documents, err := mongodb.FindDocuments(ctx, schedulesCollection, bson.M{}, 0, bson.M{"_id": 1})
+
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]schedulemodel.Schedule, 0, len(documents))
+
+	for _, document := range documents {
+		schedule, err := decodeSchedule(document)
+
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, *schedule)
+	}
+
+	return schedules, nil
+}
+
+// RunScheduler runs the enabled schedules stored in MongoDB as they fall due,
+// until ctx is done. A schedule runs in one process at a time however many
+// run the scheduler, and a run still going when the next one is due delays
+// it.
+func RunScheduler(ctx context.Context) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	running := make(map[string]bool)
+
+	fmt.Println("Scheduler started as", scheduleOwner)
+
+	for {
+		schedules, err := ListSchedules(ctx)
+
+		if err != nil && ctx.Err() == nil {
+			fmt.Println("Error loading schedules:", err)
+		}
+
+		now := time.Now()
+
+		for _, schedule := range schedules {
+			if !schedule.Enabled {
+				continue
+			}
+
+			spec, err := scheduler.Parse(schedule.Spec)
+
+			if err != nil {
+				fmt.Printf("Error: schedule %s: %v\n", schedule.Name, err)
+				continue
+			}
+
+			next := nextRun(spec, schedule)
+
+			if next.IsZero() || next.After(now) {
+				continue
+			}
+
+			mu.Lock()
+			if running[schedule.Name] {
+				mu.Unlock()
+				continue
+			}
+			running[schedule.Name] = true
+			mu.Unlock()
+
+			wg.Add(1)
+
+			go func(schedule schedulemodel.Schedule) {
+				defer wg.Done()
+
+				runSchedule(ctx, schedule, spec)
+
+				mu.Lock()
+				delete(running, schedule.Name)
+				mu.Unlock()
+			}(schedule)
+		}
+
+		if err := utils.Sleep(ctx, SchedulerTick); err != nil {
+			break
+		}
+	}
+
+	wg.Wait()
+
+	fmt.Println("Scheduler stopped")
+}
+
+// nextRun is when schedule is due. One that never ran is due a period after it
+// was created, or at once when it was stored without a creation time.
+func nextRun(spec scheduler.Schedule, schedule schedulemodel.Schedule) time.Time {
+	switch {
+	case schedule.LastRun != 0:
+		return spec.Next(time.Unix(schedule.LastRun, 0))
+	case schedule.CreatedAt != 0:
+		return spec.Next(time.Unix(schedule.CreatedAt, 0))
+	default:
+		return time.Now()
+	}
+}
+
+// runSchedule runs schedule if no other process does, then records how the
+// run went.
+func runSchedule(ctx context.Context, schedule schedulemodel.Schedule, spec scheduler.Schedule) {
+	started := time.Now()
+
+	locked, err := lockSchedule(ctx, schedule, started)
+
+	if err != nil {
+		fmt.Printf("Error locking schedule %s: %v\n", schedule.Name, err)
+		return
+	}
+
+	if !locked {
+		return
+	}
+
+	fmt.Printf("Schedule %s: %s %s started\n", schedule.Name, schedule.Action, schedule.Chain)
+
+	// Keep the lock while the run lasts
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	heartbeat := make(chan struct{})
+
+	go func() {
+		defer close(heartbeat)
+
+		for utils.Sleep(heartbeatCtx, scheduleLease/3) == nil {
+			update := bson.M{"$set": bson.M{"lockeduntil": time.Now().Add(scheduleLease).Unix()}}
+
+			if _, err := mongodb.UpdateOne(heartbeatCtx, schedulesCollection, bson.M{"_id": schedule.Name, "lockedby": scheduleOwner}, update); err != nil && heartbeatCtx.Err() == nil {
+				fmt.Printf("Error renewing lock of schedule %s: %v\n", schedule.Name, err)
+			}
+		}
+	}()
+
+	job, runErr := runScheduleAction(ctx, schedule)
+
+	stopHeartbeat()
+	<-heartbeat
+
+	record := bson.M{
+		"lastduration": int64(time.Since(started) / time.Second),
+		"lastoutcome":  schedulemodel.OutcomeDone,
+		"lasterror":    "",
+		"nextrun":      spec.Next(started).Unix(),
+	}
+
+	switch {
+	case errors.Is(runErr, context.Canceled), errors.Is(runErr, context.DeadlineExceeded):
+		record["lastoutcome"] = schedulemodel.OutcomeCancelled
+		record["lasterror"] = runErr.Error()
+	case runErr != nil:
+		record["lastoutcome"] = schedulemodel.OutcomeFailed
+		record["lasterror"] = runErr.Error()
+	case job != nil:
+		if failed := failedItems(job); failed > 0 {
+			record["lasterror"] = fmt.Sprintf("%d/%d wallet(s) failed", failed, len(job.Items))
+		}
+	}
+
+	if job != nil {
+		record["lastjob"] = job.ID
+	}
+
+	update := bson.M{
+		"$set":   record,
+		"$unset": bson.M{"lockedby": "", "lockeduntil": ""},
+	}
+
+	if _, err := mongodb.UpdateOne(context.WithoutCancel(ctx), schedulesCollection, bson.M{"_id": schedule.Name, "lockedby": scheduleOwner}, update); err != nil {
+		fmt.Printf("Error recording run of schedule %s: %v\n", schedule.Name, err)
+	}
+
+	fmt.Printf("Schedule %s: %s in %v\n", schedule.Name, record["lastoutcome"], time.Since(started).Round(time.Second))
+}
+
+// lockSchedule takes the lock of schedule and records started as its last
+// run. It fails to when another process holds the lock, or ran the schedule
+// since it was read.
+func lockSchedule(ctx context.Context, schedule schedulemodel.Schedule, started time.Time) (bool, error) {
+	var lastRun interface{}
+
+	if schedule.LastRun != 0 {
+		lastRun = schedule.LastRun
+	}
+
+	filter := bson.M{
+		"_id":     schedule.Name,
+		"enabled": true,
+		"lastrun": lastRun,
+		"$or": []bson.M{
+			{"lockeduntil": bson.M{"$exists": false}},
+			{"lockeduntil": bson.M{"$lte": started.Unix()}},
+		},
+	}
+
+	update := bson.M{"$set": bson.M{
+		"lockedby":    scheduleOwner,
+		"lockeduntil": started.Add(scheduleLease).Unix(),
+		"lastrun":     started.Unix(),
+	}}
+
+	matched, err := mongodb.UpdateOne(ctx, schedulesCollection, filter, update)
+
+	return matched == 1, err
+}
+
+// runScheduleAction runs the scan of schedule and returns its job.
+func runScheduleAction(ctx context.Context, schedule schedulemodel.Schedule) (*jobmodel.Job, error) {
+	switch schedule.Action {
+	case schedulemodel.ActionRescan:
+		filter := bson.M(schedule.Filter)
+		incremental := true
+
+		if filter == nil {
+			filter = bson.M{}
+		}
+
+		switch schedule.Mode {
+		case "full":
+			incremental = false
+		case "backfill-usd":
+			filter = bson.M{"$and": []bson.M{filter, BackfillUSDFilter}}
+			incremental = false
+		}
+
+		return ReScanWalletPNLJob(ctx, schedule.Chain, filter, incremental)
+	default:
+		return nil, fmt.Errorf("unknown schedule action: %q", schedule.Action)
+	}
+}
+
+func failedItems(job *jobmodel.Job) int {
+	failed := 0
+
+	for _, item := range job.Items {
+		if item.Status == jobmodel.StatusFailed {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func decodeSchedule(document bson.M) (*schedulemodel.Schedule, error) {
+	raw, err := bson.Marshal(document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule schedulemodel.Schedule
+
+	if err := bson.Unmarshal(raw, &schedule); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"pnl-scan-tool/core/dexscreener"
+	gmgnai "pnl-scan-tool/core/gmgn.ai"
+	"pnl-scan-tool/core/photon"
+	"pnl-scan-tool/core/solscan"
+	"pnl-scan-tool/core/source"
+	"pnl-scan-tool/package/chains"
+	"strings"
+)
+
+// sources are the trade sources that can be named in TRADE_SOURCE.
+var sources = map[string]source.TradeSource{
+	"gmgn":        gmgnai.Source{NativePriceUSD: nativePriceUSD},
+	"photon":      photon.Source{},
+	"solscan":     solscan.Source{},
+	"dexscreener": dexscreener.Source{},
+}
+
+// Source is the trade source used by the scans. It is set from the config
+// with NewSource and defaults to gmgn.ai, with DEX Screener for prices, then
+// to the EVM nodes in EVM_RPC_URLS for the chains gmgn.ai does not cover.
+var Source source.TradeSource = source.Fallback{gmgnai.Source{NativePriceUSD: nativePriceUSD}, dexscreener.Source{}}
+
+// RegisterSource makes a source that needs configuration, such as an RPC
+// endpoint, available to NewSource under name.
+func RegisterSource(name string, s source.TradeSource) {
+	sources[name] = s
+}
+
+// NewSource builds a trade source from a comma separated list of source
+// names, tried in order, e.g. "solanarpc,gmgn,dexscreener".
+func NewSource(names string) (source.TradeSource, error) {
+	var fallback source.Fallback
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		s, ok := sources[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown trade source: %s", name)
+		}
+
+		fallback = append(fallback, s)
+	}
+
+	if len(fallback) == 0 {
+		return nil, fmt.Errorf("no trade source given")
+	}
+
+	return fallback, nil
+}
+
+// nativePriceUSD prices the native coin of a chain from DEX Screener.
+func nativePriceUSD(ctx context.Context, chainID string) (float64, error) {
+	chain, err := chains.Get(chainID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	price, err := dexscreener.Source{}.Price(ctx, chain.ID, chain.WrappedNative)
+
+	return price.PriceUSD, err
+}
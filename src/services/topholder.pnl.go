@@ -1,49 +1,85 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	gmgnai "pnl-scan-tool/core/gmgn.ai"
+	"pnl-scan-tool/package/chains"
 	"pnl-scan-tool/package/files"
 	"pnl-scan-tool/platform/database/mongodb"
+	jobmodel "pnl-scan-tool/src/model/job.model"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-func TopHoldersScan(chain string, tokenAddress string) {
+func TopHoldersScan(ctx context.Context, chainID string, tokenAddress string) {
+
+	chain, err := chains.Get(chainID)
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	tokenAddress = chain.NormalizeAddress(tokenAddress)
 
 	filter := bson.M{"tokenaddress": tokenAddress, "scantype": "topholders"}
 
-	_, err := mongodb.FindOne("token_scan", filter)
+	_, err = mongodb.FindOne(ctx, chain.TokenScanCollection(), filter)
 
 	if err == nil {
 		fmt.Println("Token address already exists in the database.")
 		return
 	}
 
-	topHolers := gmgnai.TopHoldersToken(chain, tokenAddress)
+	topHolers, err := Source.TopHolders(ctx, chain.ID, tokenAddress)
+
+	if err != nil {
+		fmt.Println("Error: " + err.Error())
+		return
+	}
+
+	job := &jobmodel.Job{Kind: jobmodel.KindTopHolders, Chain: chain.ID, Token: tokenAddress}
 
-	for _, holder := range topHolers {
+	if err := createJob(ctx, job, topHolers); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 
-		fmt.Println("Holder: " + holder.Address)
+	runTopHolders(ctx, chain, job)
+}
 
-		pnlHistory, err := DeepPNLScanSol(chain, holder.Address, 30)
+// runTopHolders scans the top holders of a job's token, then records the
+// token as scanned.
+func runTopHolders(ctx context.Context, chain chains.Chain, job *jobmodel.Job) {
+	failures, err := runJob(ctx, job, func(ctx context.Context, holder string) error {
+		fmt.Println("Holder: " + holder)
 
-		if err != nil || pnlHistory == nil {
-			//time.Sleep(1 * time.Second)
-			continue
+		if windowScanned(ctx, chain, holder) {
+			return nil
 		}
 
-		//time.Sleep(time.Duration(generateRandomInt(1000, 2000)) * time.Millisecond)
+		pnlHistory, err := DeepPNLScan(ctx, chain.ID, holder, 30, DefaultOptions)
+
+		if err != nil {
+			return err
+		}
 
-		if pnlHistory.SummaryReview.RateBigXPNL > 51 {
-			files.AppendToFile("wallet.pnl.txt", holder.Address)
+		if pnlHistory != nil && pnlHistory.SummaryReview.RateBigXPNL > 51 {
+			files.AppendToFile("wallet.pnl.txt", holder)
 		}
 
-		// time.Sleep(1 * time.Second)
+		return nil
+	})
+
+	failures.Print(len(job.Items))
+
+	// Stop before recording the token as scanned, so it can be resumed
+	if err != nil {
+		return
 	}
 
-	mongodb.InsertDocumentWithRollback("token_scan", map[string]interface{}{
-		"tokenaddress": tokenAddress,
+	mongodb.InsertDocumentWithRollback(ctx, chain.TokenScanCollection(), map[string]interface{}{
+		"tokenaddress": job.Token,
 		"scantype":     "topholders",
 	})
 }
@@ -1,115 +1,103 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	"pnl-scan-tool/core/dexscreener"
-	gmgnai "pnl-scan-tool/core/gmgn.ai"
-	"pnl-scan-tool/core/photon"
+	"pnl-scan-tool/package/chains"
 	"pnl-scan-tool/package/files"
 	"pnl-scan-tool/platform/database/mongodb"
-	"strings"
+	jobmodel "pnl-scan-tool/src/model/job.model"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-func TopTraderScan(chain string, tokenAddress string) {
+func TopTraderScan(ctx context.Context, chainID string, tokenAddress string) {
 
-	if chain == "sol" {
-		filter := bson.M{"tokenaddress": tokenAddress}
+	chain, err := chains.Get(chainID)
 
-		_, err := mongodb.FindOne("token_scan_sol", filter)
-
-		if err == nil {
-			fmt.Println("Token address already exists in the database.")
-			return
-		}
-
-		token := photon.Token{
-			TokenAddress: tokenAddress,
-		}
-
-		data, err := token.TokenInfomation()
-
-		if err != nil {
-			fmt.Println("Error: " + err.Error())
-			return
-		}
-
-		// topTraders, err := token.TopTraders(data.PoolId)
-
-		// if err != nil {
-		// 	fmt.Println("Error: " + err.Error())
-		// 	return
-		// }
-
-		topTraders := gmgnai.TopTradersToken(chain, tokenAddress)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 
-		for _, trader := range topTraders {
+	tokenAddress = chain.NormalizeAddress(tokenAddress)
 
-			fmt.Println("Trader: " + trader.Address)
+	collection := chain.TokenScanCollection()
 
-			pnlHistory, err := DeepPNLScanSol(chain, trader.Address, 30)
+	// Tokens scanned before were recorded with the provider's own token
+	// document, keyed by tokenaddress on Solana and contractaddress on ETH.
+	// Top holder scans share the collection and don't count.
+	filter := bson.M{
+		"$or": []bson.M{
+			{"tokenaddress": tokenAddress},
+			{"contractaddress": tokenAddress},
+		},
+		"scantype": bson.M{"$ne": "topholders"},
+	}
 
-			if err != nil || pnlHistory == nil {
-				//time.Sleep(1 * time.Second)
-				continue
-			}
+	_, err = mongodb.FindOne(ctx, collection, filter)
 
-			//time.Sleep(time.Duration(generateRandomInt(1000, 2000)) * time.Millisecond)
+	if err == nil {
+		fmt.Println("Token address already exists in the database.")
+		return
+	}
 
-			if pnlHistory.SummaryReview.RateBigXPNL > 51 {
-				files.AppendToFile("wallet.pnl.txt", trader.Address)
-			}
+	topTraders, err := Source.TopTraders(ctx, chain.ID, tokenAddress)
 
-			// time.Sleep(1 * time.Second)
-		}
+	if err != nil {
+		fmt.Println("Error: " + err.Error())
+		return
+	}
 
-		mongodb.InsertDocumentWithRollback("token_scan_sol", data)
-	} else if chain == "eth" {
+	job := &jobmodel.Job{Kind: jobmodel.KindTopTraders, Chain: chain.ID, Token: tokenAddress}
 
-		tokenAddress = strings.ToLower(tokenAddress)
+	if err := createJob(ctx, job, topTraders); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 
-		filter := bson.M{"contractaddress": tokenAddress}
+	runTopTraders(ctx, chain, job)
+}
 
-		_, err := mongodb.FindOne("token_scan_eth", filter)
+// runTopTraders scans the top traders of a job's token, then records the
+// token as scanned.
+func runTopTraders(ctx context.Context, chain chains.Chain, job *jobmodel.Job) {
+	failures, err := runJob(ctx, job, func(ctx context.Context, trader string) error {
+		fmt.Println("Trader: " + trader)
 
-		if err == nil {
-			fmt.Println("Token address already exists in the database.")
-			return
+		if windowScanned(ctx, chain, trader) {
+			return nil
 		}
 
-		data, err := dexscreener.TokenInfomation("ethereum", tokenAddress)
+		pnlHistory, err := DeepPNLScan(ctx, chain.ID, trader, 30, DefaultOptions)
 
 		if err != nil {
-			fmt.Println("Error: " + err.Error())
-			return
+			return err
 		}
 
-		topTraders := gmgnai.TopTradersToken(chain, tokenAddress)
-
-		for _, trader := range topTraders {
-
-			fmt.Println("Trader: " + trader.Address)
-
-			pnlHistory, err := DeepPNLScanETH(chain, trader.Address, 30)
-
-			if err != nil || pnlHistory == nil {
-				//time.Sleep(1 * time.Second)
-				continue
-			}
+		if pnlHistory != nil && pnlHistory.SummaryReview.RateBigXPNL > 51 {
+			files.AppendToFile("wallet.pnl.txt", trader)
+		}
 
-			//time.Sleep(time.Duration(generateRandomInt(1000, 2000)) * time.Millisecond)
+		return nil
+	})
 
-			if pnlHistory.SummaryReview.RateBigXPNL > 51 {
-				files.AppendToFile("wallet.pnl.txt", trader.Address)
-			}
+	failures.Print(len(job.Items))
 
-			// time.Sleep(1 * time.Second)
-		}
+	// Stop before recording the token as scanned, so it can be resumed
+	if err != nil {
+		return
+	}
 
-		mongodb.InsertDocumentWithRollback("token_scan_eth", data.QI.QuickiAudit)
+	document := bson.M{
+		"tokenaddress": job.Token,
+		"scantype":     "toptraders",
+	}
 
-	} else {
-		return
+	if price, err := Source.Price(ctx, chain.ID, job.Token); err == nil {
+		document["tokensymbol"] = price.Symbol
+		document["priceusd"] = price.PriceUSD
 	}
+
+	mongodb.InsertDocumentWithRollback(ctx, chain.TokenScanCollection(), document)
 }
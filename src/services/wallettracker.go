@@ -30,9 +30,10 @@ type ErrorResponse struct {
 }
 
 type WalletTrackerTaskManager struct {
-	pool    *workerpool.WorkerPool
-	taskMap map[string]*workerpool.Task // Task ID -> Task
-	mu      sync.Mutex
+	pool     *workerpool.WorkerPool
+	taskMap  map[string]*workerpool.Task // Task ID -> Task
+	mu       sync.Mutex
+	shutdown sync.Once
 }
 
 func NewWalletTrackerTaskManager(minWorkers, maxWorkers int, scalingInterval time.Duration) *WalletTrackerTaskManager {
@@ -53,6 +54,7 @@ func NewWalletTrackerTaskManager(minWorkers, maxWorkers int, scalingInterval tim
 // @Param task body WalletTrackerRequest true "Task Details"
 // @Success 201 {object} WalletTrackerResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Router /api/wallettracker/add [post]
 func (tm *WalletTrackerTaskManager) AddWalletTrackerHandler(ctx context.Context, telegramBot *bot.Bot) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -72,7 +74,7 @@ func (tm *WalletTrackerTaskManager) AddWalletTrackerHandler(ctx context.Context,
 		timeout := 10 * time.Second // Timeout after 10 seconds
 
 		// Create a new task
-		task := workerpool.NewTask(func(ctx context.Context) error {
+		task := workerpool.NewTask(wallet, func(ctx context.Context) error {
 			select {
 			case <-ctx.Done():
 				fmt.Printf("Task %s was cancelled or timed out\n", wallet)
@@ -117,8 +119,13 @@ func (tm *WalletTrackerTaskManager) AddWalletTrackerHandler(ctx context.Context,
 		}, priority, timeout)
 
 		// Add task to pool and map
+		if err := tm.pool.AddTask(task); err != nil {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+
 		tm.taskMap[wallet] = task
-		tm.pool.AddTask(task)
 
 		return c.Status(fiber.StatusCreated).JSON(WalletTrackerResponse{
 			Message:  "Wallet tracker added",
@@ -177,11 +184,24 @@ func (tm *WalletTrackerTaskManager) GetMetricsHandler(c *fiber.Ctx) error {
 		"tasksFailed":           metrics.TasksFailed,
 		"averageWaitTime":       metrics.AverageWaitTime.String(),
 		"averageProcessingTime": metrics.AverageProcessingTime.String(),
+		"averageLatency":        metrics.AverageLatency.String(),
+		"maxLatency":            metrics.MaxLatency.String(),
+		"tasksRetried":          metrics.TasksRetried,
+		"queueDepth":            metrics.QueueDepth,
+		"maxQueueDepth":         metrics.MaxQueueDepth,
+		"activeWorkers":         metrics.ActiveWorkers,
+		"busyWorkers":           metrics.BusyWorkers,
 	})
 }
 
+// Shutdown cancels the trackers running and stops the worker pool. The pool
+// is only shut down once, whether from the shutdown route or the server.
+func (tm *WalletTrackerTaskManager) Shutdown() {
+	tm.shutdown.Do(tm.pool.Shutdown)
+}
+
 func (tm *WalletTrackerTaskManager) ShutdownHandler(c *fiber.Ctx) error {
-	tm.pool.Shutdown()
+	tm.Shutdown()
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Worker pool is shutting down",
 	})